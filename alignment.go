@@ -0,0 +1,65 @@
+package main
+
+import "io"
+
+// An Alignment is a set of records, which can be written to and read from the
+// standard library's io plumbing (io.Copy, for instance) as fasta text
+type Alignment []FastaRecord
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+// WriteTo writes the record to w as fasta text, wrapped at DefaultLineWidth, and
+// returns the number of bytes written. It implements io.WriterTo.
+func (FR *FastaRecord) WriteTo(w io.Writer) (int64, error) {
+	buf := appendFasta(make([]byte, 0, len(FR.Seq)+len(FR.Seq)/DefaultLineWidth+len(FR.header())+4), FR.header(), FR, DefaultLineWidth)
+	n, err := w.Write(buf)
+	return int64(n), err
+}
+
+// WriteTo writes every record to w as fasta text, wrapped at DefaultLineWidth, and
+// returns the number of bytes written. It implements io.WriterTo.
+func (A Alignment) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	err := NewWriter(cw).WriteAll(A)
+	return cw.n, err
+}
+
+// ReadFrom reads records from r until EOF, appending them (unencoded) to the
+// alignment, and returns the number of bytes read. The records are not checked for
+// being the same length; use LoadAlignment for that. It implements io.ReaderFrom.
+func (A *Alignment) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countingReader{r: r}
+	reader := NewReader(cr)
+	for {
+		FR, err := reader.Read()
+		if err == io.EOF {
+			return cr.n, nil
+		} else if err != nil {
+			return cr.n, err
+		}
+		FR.Idx = len(*A)
+		*A = append(*A, FR)
+	}
+}
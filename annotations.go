@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SetAnnotation stores a value on a record under key, creating the map if needed
+func (FR *FastaRecord) SetAnnotation(key string, value any) {
+	if FR.Annotations == nil {
+		FR.Annotations = make(map[string]any)
+	}
+	FR.Annotations[key] = value
+}
+
+// GetAnnotation returns the value stored on a record under key
+func (FR *FastaRecord) GetAnnotation(key string) (any, bool) {
+	value, ok := FR.Annotations[key]
+	return value, ok
+}
+
+// DeleteAnnotation removes key from a record's annotations
+func (FR *FastaRecord) DeleteAnnotation(key string) {
+	delete(FR.Annotations, key)
+}
+
+// annotatedHeader returns the record's header with its annotations appended as
+// space-separated key=value pairs, sorted by key so that the output is stable
+func (FR *FastaRecord) annotatedHeader() string {
+	if len(FR.Annotations) == 0 {
+		return FR.header()
+	}
+	keys := make([]string, 0, len(FR.Annotations))
+	for k := range FR.Annotations {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString(FR.header())
+	for _, k := range keys {
+		fmt.Fprintf(&sb, " %s=%v", k, FR.Annotations[k])
+	}
+	return sb.String()
+}
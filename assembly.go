@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Summary statistics for a multi-record fasta file of contigs or scaffolds
+type AssemblyStats struct {
+	Count       int
+	TotalLength int
+	Largest     int
+	Smallest    int
+	Mean        float64
+	N50         int
+	L50         int
+	N90         int
+	L90         int
+	GC          float64 // GC% of the unambiguous bases
+	NCount      int     // Ns and '?'s
+	Gaps        int     // number of runs of Ns and '?'s
+	lengths     []int
+}
+
+type assemblyAccumulator struct {
+	lengths []int
+	bc      baseCounts
+	gaps    int
+}
+
+func (aa *assemblyAccumulator) add(FR *FastaRecord) {
+	aa.lengths = append(aa.lengths, len(FR.Seq))
+	bc := countBases(FR)
+	aa.bc.A += bc.A
+	aa.bc.C += bc.C
+	aa.bc.G += bc.G
+	aa.bc.T += bc.T
+	aa.bc.N += bc.N
+	inGap := false
+	for i := range FR.Seq {
+		if isN(FR.encodedAt(i)) {
+			if !inGap {
+				aa.gaps++
+			}
+			inGap = true
+		} else {
+			inGap = false
+		}
+	}
+}
+
+func (aa *assemblyAccumulator) stats() AssemblyStats {
+	sort.Sort(sort.Reverse(sort.IntSlice(aa.lengths)))
+
+	AS := AssemblyStats{
+		Count:   len(aa.lengths),
+		GC:      aa.bc.gcPercent(),
+		NCount:  aa.bc.N,
+		Gaps:    aa.gaps,
+		lengths: aa.lengths,
+	}
+	if AS.Count == 0 {
+		return AS
+	}
+	for _, l := range aa.lengths {
+		AS.TotalLength += l
+	}
+	AS.Largest = aa.lengths[0]
+	AS.Smallest = aa.lengths[len(aa.lengths)-1]
+	AS.Mean = float64(AS.TotalLength) / float64(AS.Count)
+	AS.N50, AS.L50 = AS.NX(50)
+	AS.N90, AS.L90 = AS.NX(90)
+
+	return AS
+}
+
+// NX returns the length of the shortest record such that records of that length
+// or longer make up at least x% of the total length, and the number of those records
+// (e.g. NX(50) returns N50 and L50).
+func (AS AssemblyStats) NX(x float64) (int, int) {
+	target := float64(AS.TotalLength) * x / 100
+	cumulative := 0
+	for i, l := range AS.lengths {
+		cumulative += l
+		if float64(cumulative) >= target {
+			return l, i + 1
+		}
+	}
+	return 0, 0
+}
+
+// CalcAssemblyStats reads every record from r and returns the assembly statistics.
+// Only the record lengths are kept in memory.
+func CalcAssemblyStats(r io.Reader) (AssemblyStats, error) {
+	var aa assemblyAccumulator
+	reader := NewReader(r)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return AssemblyStats{}, err
+		}
+		aa.add(&record)
+	}
+	return aa.stats(), nil
+}
+
+// AssemblyStatsFromRecords returns the assembly statistics for records that are already in memory
+func AssemblyStatsFromRecords(records []FastaRecord) AssemblyStats {
+	var aa assemblyAccumulator
+	for i := range records {
+		aa.add(&records[i])
+	}
+	return aa.stats()
+}
+
+// String formats the statistics in the same layout as the assembly-stats program
+func (AS AssemblyStats) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "sum = %d, n = %d, ave = %.2f, largest = %d\n", AS.TotalLength, AS.Count, AS.Mean, AS.Largest)
+	for _, x := range []int{50, 60, 70, 80, 90, 100} {
+		nx, lx := AS.NX(float64(x))
+		fmt.Fprintf(&sb, "N%d = %d, n = %d\n", x, nx, lx)
+	}
+	fmt.Fprintf(&sb, "N_count = %d\n", AS.NCount)
+	fmt.Fprintf(&sb, "Gaps = %d\n", AS.Gaps)
+	return sb.String()
+}
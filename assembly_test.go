@@ -0,0 +1,17 @@
+package main
+
+import "testing"
+
+func TestAssemblyStatsGapsIncludeMissing(t *testing.T) {
+	records := []FastaRecord{
+		{ID: "a", Seq: []byte("ACGTNNNNACGT??ACGT")},
+		{ID: "b", Seq: []byte("ACN?NACG")},
+	}
+	AS := AssemblyStatsFromRecords(records)
+	if AS.NCount != 9 {
+		t.Errorf("NCount = %d, want 9", AS.NCount)
+	}
+	if AS.Gaps != 3 {
+		t.Errorf("Gaps = %d, want 3", AS.Gaps)
+	}
+}
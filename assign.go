@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"strconv"
+)
+
+// The closest reference in a panel to one record
+type ReferenceAssignment struct {
+	ID             string
+	Reference      string  // the closest reference, or "" if none shares a site with the record
+	Distance       float64 // the proportion of compared sites that differ
+	Compared       int     // the sites where both have an unambiguous base
+	SecondBest     string  // the next closest reference, or "" if there isn't one
+	SecondDistance float64 // equal to Distance if the closest is a tie, NaN if there is no SecondBest
+}
+
+// assignReference finds the closest references in panel to FR. Ties go to the
+// reference that comes first in the panel.
+func assignReference(panel []FastaRecord, FR *FastaRecord) (ReferenceAssignment, error) {
+	RA := ReferenceAssignment{ID: FR.ID, Distance: math.NaN(), SecondDistance: math.NaN()}
+	for i := range panel {
+		if len(panel[i].Seq) != len(FR.Seq) {
+			return ReferenceAssignment{}, errDifferentWidths
+		}
+		compared, differences := pairDifferences(&panel[i], FR)
+		if compared == 0 {
+			continue
+		}
+		d := distanceFrom(compared, differences, DistanceP)
+		switch {
+		case RA.Reference == "" || d < RA.Distance:
+			RA.SecondBest, RA.SecondDistance = RA.Reference, RA.Distance
+			RA.Reference, RA.Distance, RA.Compared = panel[i].ID, d, compared
+		case RA.SecondBest == "" || d < RA.SecondDistance:
+			RA.SecondBest, RA.SecondDistance = panel[i].ID, d
+		}
+	}
+	return RA, nil
+}
+
+// AssignReferences compares every record streamed from r with each reference in a
+// panel aligned to it, on threads goroutines, and passes the closest (by
+// p-distance, over the sites where both have an unambiguous base) to emit in file
+// order. It is meant for quick typing of segments or serotypes against a handful
+// of representative sequences.
+func AssignReferences(panel []FastaRecord, r io.Reader, threads int, emit func(ReferenceAssignment) error) error {
+	assign := func(FR FastaRecord) (ReferenceAssignment, error) {
+		return assignReference(panel, &FR)
+	}
+	return ProcessOrdered(NewReader(r), threads, assign, emit)
+}
+
+// WriteReferenceAssignments assigns every record streamed from r to its closest
+// reference in panel, as AssignReferences, writing one tab-separated line per record
+// to w
+func WriteReferenceAssignments(w io.Writer, panel []FastaRecord, r io.Reader, threads int) error {
+
+	c := csv.NewWriter(w)
+	c.Comma = '\t'
+	if err := c.Write([]string{"id", "reference", "distance", "compared", "second_best", "second_distance"}); err != nil {
+		return err
+	}
+
+	err := AssignReferences(panel, r, threads, func(RA ReferenceAssignment) error {
+		return c.Write([]string{
+			RA.ID,
+			RA.Reference,
+			formatDistance(RA.Distance),
+			strconv.Itoa(RA.Compared),
+			RA.SecondBest,
+			formatDistance(RA.SecondDistance),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Flush()
+	return c.Error()
+}
@@ -0,0 +1,48 @@
+package main
+
+// Options for making memory use predictable when records are produced faster than
+// they are consumed
+type StreamOptions struct {
+	BufferSize  int // the capacity of the record channel
+	MaxInFlight int // the most records that can be read but not yet finished with, 0 for no limit
+}
+
+// An InFlightLimiter bounds the number of records that have been read but not yet
+// released by their consumer. A nil *InFlightLimiter imposes no limit.
+type InFlightLimiter struct {
+	slots chan struct{}
+}
+
+// NewInFlightLimiter returns a limiter allowing n records in flight, or nil (no
+// limit) if n < 1
+func NewInFlightLimiter(n int) *InFlightLimiter {
+	if n < 1 {
+		return nil
+	}
+	return &InFlightLimiter{slots: make(chan struct{}, n)}
+}
+
+// Acquire blocks until a slot is free
+func (l *InFlightLimiter) Acquire() {
+	if l != nil {
+		l.slots <- struct{}{}
+	}
+}
+
+// Release frees a slot taken by Acquire
+func (l *InFlightLimiter) Release() {
+	if l != nil {
+		<-l.slots
+	}
+}
+
+// NewStreamChannels makes the channels for StreamAlignment (or
+// StreamAlignmentLimited) with the record channel buffered to opts.BufferSize, and
+// the limiter for opts.MaxInFlight
+func NewStreamChannels(opts StreamOptions) (chan FastaRecord, chan error, chan bool, *InFlightLimiter) {
+	bufferSize := opts.BufferSize
+	if bufferSize < 0 {
+		bufferSize = 0
+	}
+	return make(chan FastaRecord, bufferSize), make(chan error), make(chan bool), NewInFlightLimiter(opts.MaxInFlight)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"io"
+)
+
+// the size of the chunks read by the block parser
+const blockSize = 1 << 20
+
+// WithBlockParsing makes the Reader read its input in large blocks and find the
+// line ends in them with bytes.IndexByte, appending sequence lines straight from
+// the block instead of allocating a copy of every line with ReadBytes. This is
+// faster on wrapped files with many short lines, and on unwrapped ones where each
+// sequence is one very long line. The records are the same either way.
+func WithBlockParsing() ReaderOption {
+	return func(r *Reader) {
+		r.block = make([]byte, 0, blockSize)
+	}
+}
+
+// fill reads the next block from the underlying reader
+func (r *Reader) fill() error {
+	for {
+		n, err := r.r.Read(r.block[:cap(r.block)])
+		r.block = r.block[:n]
+		r.pos = 0
+		if n > 0 {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// blockLine appends the rest of the current line (without its newline) to dst, or
+// just skips it if discard is true. The error is io.EOF if the input ends before
+// the newline.
+func (r *Reader) blockLine(dst []byte, discard bool) ([]byte, error) {
+	for {
+		if r.pos == len(r.block) {
+			if err := r.fill(); err != nil {
+				return dst, err
+			}
+		}
+		rest := r.block[r.pos:]
+		if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+			if !discard {
+				dst = append(dst, rest[:i]...)
+			}
+			r.pos += i + 1
+			return dst, nil
+		}
+		if !discard {
+			dst = append(dst, rest...)
+		}
+		r.pos = len(r.block)
+	}
+}
+
+// atRecordEnd reports whether the next line is a header, or the input has ended
+func (r *Reader) atRecordEnd() (bool, error) {
+	if r.pos == len(r.block) {
+		if err := r.fill(); err == io.EOF {
+			return true, nil
+		} else if err != nil {
+			return false, err
+		}
+	}
+	return r.block[r.pos] == '>', nil
+}
+
+// skipBlockSequence is the block parser's equivalent of skipSequence
+func (r *Reader) skipBlockSequence() error {
+	for {
+		end, err := r.atRecordEnd()
+		if err != nil || end {
+			return err
+		}
+		if _, err = r.blockLine(nil, true); err != nil && err != io.EOF {
+			return err
+		}
+	}
+}
+
+// readBlock is the block parser's equivalent of read
+func (r *Reader) readBlock() (FastaRecord, error) {
+
+	var (
+		FR  FastaRecord
+		err error
+	)
+
+	for {
+		// as in read, a header without a newline at the very end of the file is a
+		// record with an empty sequence
+		r.header, err = r.blockLine(r.header[:0], false)
+		if err == io.EOF && len(r.header) > 0 {
+			err = nil
+		}
+		if err != nil {
+			return FastaRecord{}, err
+		}
+		line := r.header
+		if len(line) == 0 || line[0] != '>' {
+			return FastaRecord{}, errBadlyFormedFasta
+		}
+
+		line, err = r.cleanHeader(line[1:])
+		if err != nil {
+			return FastaRecord{}, err
+		}
+
+		r.splitHeader(&FR, line)
+
+		if r.headerFilter == nil || r.headerFilter(FR.ID, FR.Description) {
+			break
+		}
+
+		// discard unwanted records and start again on the next header
+		if err = r.skipBlockSequence(); err != nil {
+			return FastaRecord{}, err
+		}
+		FR = FastaRecord{}
+	}
+
+	buffer := r.newSeqBuffer()
+
+	for {
+		end, err := r.atRecordEnd()
+		if err != nil {
+			return FastaRecord{}, err
+		} else if end {
+			break
+		}
+
+		start := len(buffer)
+		buffer, err = r.blockLine(buffer, false)
+		if err != nil && err != io.EOF {
+			return FastaRecord{}, err
+		}
+		if r.encode {
+			if err = r.encodeLine(buffer[start:]); err != nil {
+				return FastaRecord{}, err
+			}
+		}
+	}
+
+	if buffer == nil {
+		buffer = []byte{}
+	}
+	FR.Seq = buffer
+	FR.encoded = r.encode
+
+	return FR, nil
+}
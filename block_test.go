@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+// makeGenomes returns n random genomes of length bases as fasta text, with the
+// sequences wrapped every width bases (or not at all if width < 1)
+func makeGenomes(n, length, width int) []byte {
+	rng := rand.New(rand.NewSource(1))
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.LineWidth = width
+	for i := 0; i < n; i++ {
+		seq := make([]byte, length)
+		for j := range seq {
+			seq[j] = "ACGT"[rng.Intn(4)]
+		}
+		w.Write(FastaRecord{ID: "genome" + strconv.Itoa(i), Seq: seq})
+	}
+	w.Flush()
+	return buf.Bytes()
+}
+
+func benchmarkReader(b *testing.B, width int, opts ...ReaderOption) {
+	data := makeGenomes(20, 30000, width)
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := NewReader(bytes.NewReader(data), opts...)
+		for {
+			_, err := r.Read()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkReaderLines60(b *testing.B)        { benchmarkReader(b, 60) }
+func BenchmarkReaderLines80(b *testing.B)        { benchmarkReader(b, 80) }
+func BenchmarkReaderLinesUnwrapped(b *testing.B) { benchmarkReader(b, 0) }
+
+func BenchmarkReaderBlocks60(b *testing.B)        { benchmarkReader(b, 60, WithBlockParsing()) }
+func BenchmarkReaderBlocks80(b *testing.B)        { benchmarkReader(b, 80, WithBlockParsing()) }
+func BenchmarkReaderBlocksUnwrapped(b *testing.B) { benchmarkReader(b, 0, WithBlockParsing()) }
+
+func TestBlockParsingMatchesLines(t *testing.T) {
+	for _, width := range []int{60, 80, 0} {
+		data := makeGenomes(5, 1000, width)
+		lines, blocks := NewReader(bytes.NewReader(data)), NewReader(bytes.NewReader(data), WithBlockParsing())
+		for {
+			a, errA := lines.Read()
+			b, errB := blocks.Read()
+			if errA != errB {
+				t.Fatalf("width %d: errors %v and %v", width, errA, errB)
+			}
+			if errA == io.EOF {
+				break
+			}
+			if a.ID != b.ID || a.Description != b.Description || !bytes.Equal(a.Seq, b.Seq) {
+				t.Fatalf("width %d: records differ at %s", width, a.ID)
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+var (
+	errEmptyID           = errors.New("Record ID is empty")
+	errBadID             = errors.New("Record ID contains whitespace")
+	errBadDescription    = errors.New("Record description contains a line break")
+	errInvalidNucleotide = errors.New("Sequence contains an invalid nucleotide")
+)
+
+// A RecordBuilder constructs a record piece by piece, checking when Build is called
+// that the record could be written out and read back in unchanged. The zero value
+// is ready to use, and builds unencoded records.
+type RecordBuilder struct {
+	Encode bool // encode the sequence when the record is built
+
+	id          string
+	description string
+	seq         []byte
+}
+
+// SetID sets the record's ID, which must not be empty or contain whitespace
+func (RB *RecordBuilder) SetID(id string) {
+	RB.id = id
+}
+
+// SetDescription sets the text that follows the ID in the header. The record's
+// Description will be the ID and this text, separated by a space, as it would be if
+// the record had been read from a file.
+func (RB *RecordBuilder) SetDescription(description string) {
+	RB.description = description
+}
+
+// AppendSeq appends (plain, unencoded) nucleotides to the sequence
+func (RB *RecordBuilder) AppendSeq(seq []byte) {
+	RB.seq = append(RB.seq, seq...)
+}
+
+// AppendString appends (plain, unencoded) nucleotides to the sequence
+func (RB *RecordBuilder) AppendString(seq string) {
+	RB.seq = append(RB.seq, seq...)
+}
+
+// Len returns the length of the sequence so far
+func (RB *RecordBuilder) Len() int {
+	return len(RB.seq)
+}
+
+// Reset clears the builder, apart from Encode, so that it can build another record
+func (RB *RecordBuilder) Reset() {
+	RB.id = ""
+	RB.description = ""
+	RB.seq = nil
+}
+
+// Build checks the ID, description and sequence and returns the record, encoded if
+// Encode is set. On success the builder is Reset, and the record takes over its
+// sequence buffer.
+func (RB *RecordBuilder) Build() (FastaRecord, error) {
+	if RB.id == "" {
+		return FastaRecord{}, errEmptyID
+	}
+	if strings.IndexFunc(RB.id, unicode.IsSpace) >= 0 {
+		return FastaRecord{}, errBadID
+	}
+	if strings.ContainsAny(RB.description, "\r\n") {
+		return FastaRecord{}, errBadDescription
+	}
+	for _, nuc := range RB.seq {
+		if encodingArray[nuc] == 0 {
+			return FastaRecord{}, errInvalidNucleotide
+		}
+	}
+
+	FR := FastaRecord{ID: RB.id, Seq: RB.seq}
+	if RB.seq == nil {
+		FR.Seq = []byte{}
+	}
+	if description := strings.TrimSpace(RB.description); description != "" {
+		FR.Description = RB.id + " " + description
+	}
+	if RB.Encode {
+		FR.MustEncode()
+	}
+
+	RB.Reset()
+	return FR, nil
+}
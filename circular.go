@@ -0,0 +1,110 @@
+package main
+
+import "errors"
+
+var (
+	errNotCircular = errors.New("Record is not circular")
+	errSeqBounds   = errors.New("Coordinates are outside the sequence")
+)
+
+// Rotate moves the origin of a circular record to position origin (0-based), in
+// place, so that the base at origin becomes the first base
+func (FR *FastaRecord) Rotate(origin int) error {
+	if !FR.Circular {
+		return errNotCircular
+	}
+	if origin < 0 || origin >= len(FR.Seq) {
+		return errSeqBounds
+	}
+	rotated := make([]byte, len(FR.Seq))
+	n := copy(rotated, FR.Seq[origin:])
+	copy(rotated[n:], FR.Seq[:origin])
+	FR.Seq = rotated
+	return nil
+}
+
+// Subseq returns a copy of the bases [start, end) (0-based). For a circular record,
+// start may be greater than end, in which case the subsequence runs across the
+// origin.
+func (FR *FastaRecord) Subseq(start, end int) ([]byte, error) {
+	if start < 0 || end < 0 || start > len(FR.Seq) || end > len(FR.Seq) {
+		return []byte{}, errSeqBounds
+	}
+	if start <= end {
+		seq := make([]byte, end-start)
+		copy(seq, FR.Seq[start:end])
+		return seq, nil
+	}
+	if !FR.Circular {
+		return []byte{}, errNotCircular
+	}
+	seq := make([]byte, 0, len(FR.Seq)-start+end)
+	seq = append(seq, FR.Seq[start:]...)
+	seq = append(seq, FR.Seq[:end]...)
+	return seq, nil
+}
+
+// One match of a motif
+type MotifHit struct {
+	Pos    int  // 0-based start of the match on the plus strand
+	Strand byte // '+' or '-'
+}
+
+// FindMotif returns every position where motif (given unencoded, and which may
+// contain IUPAC ambiguity codes) matches the record. A base in the record matches a
+// motif character if every base it could be is allowed by that character, so an N
+// in the record only matches an N in the motif. For circular records, matches that
+// run across the origin are found too. If bothStrands is set, the reverse
+// complement of the motif is searched for as well.
+func (FR *FastaRecord) FindMotif(motif []byte, bothStrands bool) []MotifHit {
+
+	hits := make([]MotifHit, 0)
+	L, m := len(FR.Seq), len(motif)
+	if m == 0 || m > L {
+		return hits
+	}
+
+	patterns := [][]byte{encodeMotif(motif)}
+	strands := []byte{'+'}
+	if bothStrands {
+		rc := encodeMotif(motif)
+		reverseComplementBytes(rc, true)
+		patterns = append(patterns, rc)
+		strands = append(strands, '-')
+	}
+
+	last := L - m
+	if FR.Circular {
+		last = L - 1
+	}
+
+	for p, pattern := range patterns {
+		for pos := 0; pos <= last; pos++ {
+			matched := true
+			for j, e := range pattern {
+				s := FR.encodedAt((pos + j) % L)
+				if s == 0 || s&^e&0xf0 != 0 || (e == 244) != (s == 244) {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				hits = append(hits, MotifHit{Pos: pos, Strand: strands[p]})
+			}
+		}
+		// a palindromic motif would otherwise be reported twice
+		if p == 1 && string(patterns[0]) == string(patterns[1]) {
+			hits = hits[:len(hits)/2]
+		}
+	}
+
+	return hits
+}
+
+func encodeMotif(motif []byte) []byte {
+	e := make([]byte, len(motif))
+	for i, c := range motif {
+		e[i] = encodingArray[c]
+	}
+	return e
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+var (
+	errUnknownCommand = errors.New("Unknown command")
+	errBadSortKey     = errors.New("Sort key must be id or length")
+)
+
+const usage = `usage: fastaigo <command> [options]
+
+commands:
+  sort    sort a fasta file by ID or length, using temporary files if it is big
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		if err != flag.ErrHelp {
+			fmt.Fprintln(os.Stderr, "fastaigo:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// run dispatches the command line args (without the program name) to a command
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return flag.ErrHelp
+	}
+	switch args[0] {
+	case "sort":
+		return sortCommand(args[1:], stdin, stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return nil
+	}
+	fmt.Fprint(stderr, usage)
+	return errUnknownCommand
+}
+
+// sortCommand is ExternalSort on a file (which may be gzipped) or stdin
+func sortCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+
+	fs := flag.NewFlagSet("sort", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: fastaigo sort [options] [in.fasta[.gz]]")
+		fs.PrintDefaults()
+	}
+	by := fs.String("by", "id", "what to sort by: id or length")
+	descending := fs.Bool("reverse", false, "sort in descending order")
+	maxMemory := fs.Int64("max-memory", 256, "roughly how many MB of records to sort in memory at once")
+	tempDir := fs.String("tmpdir", "", "where to write temporary files (default the system temporary directory)")
+	out := fs.String("o", "", "output file (default stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	opts := ExternalSortOptions{Descending: *descending, MaxMemory: *maxMemory << 20, TempDir: *tempDir}
+	switch *by {
+	case "id":
+		opts.Key = SortByID
+	case "length":
+		opts.Key = SortByLength
+	default:
+		return errBadSortKey
+	}
+
+	in := stdin
+	if fs.NArg() == 1 && fs.Arg(0) != "-" {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	in, err := Decompress(in)
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		return ExternalSort(in, stdout, opts)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err = ExternalSort(in, f, opts); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
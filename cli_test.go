@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSortCommand(t *testing.T) {
+	in := ">c\nACGTAC\n>a\nA\n>b\nACG\n"
+
+	var out, stderr bytes.Buffer
+	if err := run([]string{"sort", "-by", "length", "-reverse"}, strings.NewReader(in), &out, &stderr); err != nil {
+		t.Fatal(err, stderr.String())
+	}
+	if want := ">c\nACGTAC\n>b\nACG\n>a\nA\n"; out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+
+	dir := t.TempDir()
+	inPath, outPath := filepath.Join(dir, "in.fa"), filepath.Join(dir, "out.fa")
+	if err := os.WriteFile(inPath, []byte(in), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := run([]string{"sort", "-o", outPath, inPath}, nil, &out, &stderr); err != nil {
+		t.Fatal(err, stderr.String())
+	}
+	got, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := ">a\nA\n>b\nACG\n>c\nACGTAC\n"; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if err := run([]string{"sort", "-by", "gc"}, strings.NewReader(in), &out, &stderr); err != errBadSortKey {
+		t.Errorf("got %v, want errBadSortKey", err)
+	}
+	if err := run([]string{"shuffle"}, strings.NewReader(in), &out, &stderr); err != errUnknownCommand {
+		t.Errorf("got %v, want errUnknownCommand", err)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import "errors"
+
+var errCodonMismatch = errors.New("Nucleotide sequence length does not match protein sequence length")
+
+// CodonAlign threads nucleotide sequences through a protein alignment, producing a
+// codon alignment (as pal2nal does): every amino acid is replaced by its codon and
+// every protein gap by three nucleotide gaps. Records are matched by ID, and the
+// output is in the order of the protein alignment.
+//
+// The protein records must not be encoded. Each nucleotide record must be the
+// ungapped coding sequence of its protein, and may have one extra trailing codon
+// (a stop codon that is not in the protein sequence), which is dropped. The output
+// records are encoded if the nucleotide records are.
+func CodonAlign(proteins []FastaRecord, nucleotides []FastaRecord) ([]FastaRecord, error) {
+
+	byID := make(map[string]*FastaRecord, len(nucleotides))
+	for i := range nucleotides {
+		byID[nucleotides[i].ID] = &nucleotides[i]
+	}
+
+	aligned := make([]FastaRecord, 0, len(proteins))
+
+	for _, prot := range proteins {
+		nuc, ok := byID[prot.ID]
+		if !ok {
+			return []FastaRecord{}, errRecordNotFound
+		}
+
+		var gap byte = '-'
+		if nuc.encoded {
+			gap = 244
+		}
+
+		seq := make([]byte, 0, 3*len(prot.Seq))
+		p := 0
+		for _, aa := range prot.Seq {
+			if aa == '-' || aa == '.' {
+				seq = append(seq, gap, gap, gap)
+				continue
+			}
+			if p+3 > len(nuc.Seq) {
+				return []FastaRecord{}, errCodonMismatch
+			}
+			seq = append(seq, nuc.Seq[p:p+3]...)
+			p += 3
+		}
+		if remaining := len(nuc.Seq) - p; remaining != 0 && remaining != 3 {
+			return []FastaRecord{}, errCodonMismatch
+		}
+
+		aligned = append(aligned, FastaRecord{
+			ID:          nuc.ID,
+			Description: nuc.Description,
+			idSep:       nuc.idSep,
+			Seq:         seq,
+			Score:       nuc.Score,
+			Idx:         len(aligned),
+			encoded:     nuc.encoded,
+		})
+	}
+
+	return aligned, nil
+}
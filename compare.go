@@ -0,0 +1,83 @@
+package main
+
+// A record that is in both alignments but with a different sequence
+type RecordDifference struct {
+	ID        string
+	Positions []int // the 0-based columns that differ (up to the shorter length)
+	LengthA   int
+	LengthB   int
+}
+
+// The result of comparing two alignments with CompareAlignments
+type AlignmentComparison struct {
+	WidthA    int // the length of the first record, or 0 for an empty alignment
+	WidthB    int
+	OnlyInA   []string // IDs in the first alignment but not the second, in order
+	OnlyInB   []string
+	Differing []RecordDifference // in the order of the first alignment
+	Identical int                // the number of records in both with the same sequence
+}
+
+// Same reports whether the alignments have the same records with the same sequences
+// (ignoring their order)
+func (AC *AlignmentComparison) Same() bool {
+	return AC.WidthA == AC.WidthB && len(AC.OnlyInA) == 0 && len(AC.OnlyInB) == 0 && len(AC.Differing) == 0
+}
+
+// CompareAlignments compares two alignments (encoded or not) by record ID, e.g. to
+// check a pipeline's output against a previous run. Sequences are compared as
+// uppercase, decoded text, so encoding and case don't count as differences but
+// everything else (including N and gaps) does. It returns errDuplicateID if an ID
+// appears twice in either alignment.
+func CompareAlignments(a, b []FastaRecord) (AlignmentComparison, error) {
+
+	AC := AlignmentComparison{OnlyInA: make([]string, 0), OnlyInB: make([]string, 0), Differing: make([]RecordDifference, 0)}
+	if len(a) > 0 {
+		AC.WidthA = len(a[0].Seq)
+	}
+	if len(b) > 0 {
+		AC.WidthB = len(b[0].Seq)
+	}
+
+	inA := make(map[string]bool, len(a))
+	for _, FR := range a {
+		if inA[FR.ID] {
+			return AlignmentComparison{}, errDuplicateID
+		}
+		inA[FR.ID] = true
+	}
+	inB := make(map[string]int, len(b))
+	for i, FR := range b {
+		if _, ok := inB[FR.ID]; ok {
+			return AlignmentComparison{}, errDuplicateID
+		}
+		inB[FR.ID] = i
+	}
+
+	for i := range a {
+		j, ok := inB[a[i].ID]
+		if !ok {
+			AC.OnlyInA = append(AC.OnlyInA, a[i].ID)
+			continue
+		}
+		RD := RecordDifference{ID: a[i].ID, Positions: make([]int, 0), LengthA: len(a[i].Seq), LengthB: len(b[j].Seq)}
+		for k := 0; k < RD.LengthA && k < RD.LengthB; k++ {
+			if a[i].residue(k) != b[j].residue(k) {
+				RD.Positions = append(RD.Positions, k)
+			}
+		}
+		if len(RD.Positions) > 0 || RD.LengthA != RD.LengthB {
+			AC.Differing = append(AC.Differing, RD)
+		} else {
+			AC.Identical++
+		}
+	}
+
+	for _, FR := range b {
+		if !inA[FR.ID] {
+			AC.OnlyInB = append(AC.OnlyInB, FR.ID)
+		}
+	}
+
+	return AC, nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import "errors"
+
+// How masked positions are written
+type MaskMode int
+
+const (
+	SoftMask MaskMode = iota // lowercase, the sequence must not be encoded
+	HardMask                 // replace with N
+)
+
+var errSoftMaskEncoded = errors.New("Cannot soft-mask an encoded record")
+
+// Options for DustMask
+type DustOptions struct {
+	Window         int     // length of the scoring window
+	Threshold      float64 // windows scoring above this are masked
+	MinHomopolymer int     // runs of one base at least this long are masked, 0 to disable
+	Mode           MaskMode
+}
+
+// DefaultDustOptions returns the window and threshold used by the original DUST
+// program, with homopolymer masking turned off
+func DefaultDustOptions() DustOptions {
+	return DustOptions{Window: 64, Threshold: 20, Mode: SoftMask}
+}
+
+// the index (0-3) of an unambiguous encoded base, or -1
+func baseIndex(e byte) int {
+	switch e {
+	case 136:
+		return 0
+	case 40:
+		return 1
+	case 72:
+		return 2
+	case 24:
+		return 3
+	}
+	return -1
+}
+
+// DustMask finds low-complexity regions in a record using a DUST-like triplet
+// score, plus (optionally) long homopolymer runs, and returns a masked copy of the
+// record along with the masked intervals.
+//
+// The score of a stretch of sequence is 10 * sum(c * (c - 1) / 2) / (l - 1), where
+// c is the count of each of the 64 triplets in the stretch and l is the number of
+// triplets. Within each window of opts.Window bases, the highest-scoring stretch is
+// masked if its score is above opts.Threshold. Triplets that include anything other
+// than A, C, G or T are not counted.
+func DustMask(FR FastaRecord, opts DustOptions) (FastaRecord, []Interval, error) {
+
+	if opts.Mode == SoftMask && FR.encoded {
+		return FastaRecord{}, []Interval{}, errSoftMaskEncoded
+	}
+
+	intervals := make([]Interval, 0)
+	L := len(FR.Seq)
+
+	w := opts.Window
+	if w > L {
+		w = L
+	}
+
+	// triplet code starting at position i, or -1
+	triplet := func(i int) int {
+		a, b, c := baseIndex(FR.encodedAt(i)), baseIndex(FR.encodedAt(i+1)), baseIndex(FR.encodedAt(i+2))
+		if a < 0 || b < 0 || c < 0 {
+			return -1
+		}
+		return a<<4 | b<<2 | c
+	}
+
+	// as in the original program, windows overlap by half their length, and in each
+	// one we mask the highest-scoring stretch if it scores above the threshold
+	if w >= 4 {
+		for start := 0; ; start += w / 2 {
+			if start+w > L {
+				start = L - w
+			}
+			if best, ok := bestDustInterval(start, w, opts.Threshold, triplet); ok {
+				intervals = append(intervals, best)
+			}
+			if start+w == L {
+				break
+			}
+		}
+	}
+
+	if opts.MinHomopolymer > 0 {
+		for i := 0; i < L; {
+			j := i + 1
+			e := FR.encodedAt(i)
+			for j < L && FR.encodedAt(j) == e {
+				j++
+			}
+			if baseIndex(e) >= 0 && j-i >= opts.MinHomopolymer {
+				intervals = append(intervals, Interval{Start: i, End: j})
+			}
+			i = j
+		}
+	}
+
+	intervals = mergeIntervals(intervals)
+
+	masked := FR
+	masked.Detach()
+	for _, iv := range intervals {
+		masked.maskRange(iv.Start, iv.End, opts.Mode)
+	}
+
+	return masked, intervals, nil
+}
+
+// bestDustInterval returns the highest scoring stretch of the window of length w
+// beginning at start, and whether its score is above threshold
+func bestDustInterval(start, w int, threshold float64, triplet func(int) int) (Interval, bool) {
+
+	l := w - 2
+	best := threshold
+	var bestIv Interval
+	found := false
+
+	for a := 0; a < l; a++ {
+		var counts [64]int
+		r := 0
+		for b := a; b < l; b++ {
+			if t := triplet(start + b); t >= 0 {
+				r += counts[t]
+				counts[t]++
+			}
+			n := b - a + 1
+			if n < 2 {
+				continue
+			}
+			score := 10 * float64(r) / float64(n-1)
+			if score > best || (found && score == best && n+2 > bestIv.End-bestIv.Start) {
+				best = score
+				bestIv = Interval{Start: start + a, End: start + b + 3}
+				found = true
+			}
+		}
+	}
+
+	return bestIv, found
+}
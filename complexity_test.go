@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestDustMaskCopiesAnnotations(t *testing.T) {
+	FR := FastaRecord{ID: "a", Seq: []byte("ACGTAAAAAAAAAAACGT"), Annotations: map[string]any{"k": 1}}
+	opts := DefaultDustOptions()
+	opts.MinHomopolymer = 8
+	masked, intervals, err := DustMask(FR, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(intervals) != 1 || string(FR.Seq) != "ACGTAAAAAAAAAAACGT" {
+		t.Fatalf("got intervals %v, input %s", intervals, FR.Seq)
+	}
+	masked.Annotations["k"] = 2
+	masked.Annotations["new"] = true
+	if FR.Annotations["k"] != 1 || len(FR.Annotations) != 1 {
+		t.Errorf("masking changed the input's annotations: %v", FR.Annotations)
+	}
+}
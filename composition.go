@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// Codon and dinucleotide counts for one record (usually a CDS), or several added
+// together
+type Composition struct {
+	ID            string
+	Codons        [64]int   // in-frame codons, in NCBI table order (see GeneticCode)
+	Dinucleotides [4][4]int // adjacent pairs of bases, indexed A, C, G, T
+	Bases         [4]int    // A, C, G and T
+}
+
+// the bases in NCBI table order (for naming codons), and in A, C, G, T order
+const (
+	ncbiBases = "TCAG"
+	acgt      = "ACGT"
+)
+
+// CalcComposition counts the codons (reading in frame from the first base) and the
+// dinucleotides in a record, encoded or not. Codons and dinucleotides that include
+// anything other than A, C, G or T are not counted.
+func CalcComposition(FR FastaRecord) Composition {
+	return CalcSequenceComposition(FR.ID, FR.Sequence())
+}
+
+// CalcSequenceComposition is CalcComposition for any Sequence
+func CalcSequenceComposition(id string, s Sequence) Composition {
+
+	C := Composition{ID: id}
+	v := viewOf(s)
+
+	for i := 0; i+2 < v.n; i += 3 {
+		a, b, c := ncbiIndex(v.at(i)), ncbiIndex(v.at(i+1)), ncbiIndex(v.at(i+2))
+		if a >= 0 && b >= 0 && c >= 0 {
+			C.Codons[a*16+b*4+c]++
+		}
+	}
+
+	prev := -1
+	for i := 0; i < v.n; i++ {
+		b := baseIndex(v.at(i))
+		if b >= 0 {
+			C.Bases[b]++
+			if prev >= 0 {
+				C.Dinucleotides[prev][b]++
+			}
+		}
+		prev = b
+	}
+
+	return C
+}
+
+// Add adds the counts from another Composition, e.g. to pool every CDS in a genome
+func (C *Composition) Add(other Composition) {
+	for i := range C.Codons {
+		C.Codons[i] += other.Codons[i]
+	}
+	for i := range C.Dinucleotides {
+		for j := range C.Dinucleotides[i] {
+			C.Dinucleotides[i][j] += other.Dinucleotides[i][j]
+		}
+	}
+	for i := range C.Bases {
+		C.Bases[i] += other.Bases[i]
+	}
+}
+
+// SumCompositions adds up several Compositions into one with the given ID
+func SumCompositions(comps []Composition, id string) Composition {
+	total := Composition{ID: id}
+	for _, C := range comps {
+		total.Add(C)
+	}
+	return total
+}
+
+// RSCU returns the relative synonymous codon usage of each codon (in NCBI table
+// order): its count divided by the mean count of the codons for the same amino acid
+// (or stop). An amino acid that is never used gets 0 for all of its codons.
+func (C *Composition) RSCU(code GeneticCode) [64]float64 {
+
+	var totals, synonyms [256]int
+	for i, aa := range code {
+		totals[aa] += C.Codons[i]
+		synonyms[aa]++
+	}
+
+	var rscu [64]float64
+	for i, aa := range code {
+		if totals[aa] > 0 {
+			rscu[i] = float64(C.Codons[i]) * float64(synonyms[aa]) / float64(totals[aa])
+		}
+	}
+	return rscu
+}
+
+// OddsRatio returns the dinucleotide odds ratio of x followed by y (e.g. 'C', 'G'
+// for the CpG observed/expected ratio): the dinucleotide's frequency divided by the
+// product of the two bases' frequencies. It is 0 if there is nothing to compare.
+func (C *Composition) OddsRatio(x, y byte) float64 {
+
+	i, j := baseIndex(encodingArray[x]), baseIndex(encodingArray[y])
+	if i < 0 || j < 0 {
+		return 0
+	}
+
+	pairs, bases := 0, 0
+	for a := range C.Dinucleotides {
+		bases += C.Bases[a]
+		for b := range C.Dinucleotides[a] {
+			pairs += C.Dinucleotides[a][b]
+		}
+	}
+	if pairs == 0 || C.Bases[i] == 0 || C.Bases[j] == 0 {
+		return 0
+	}
+
+	fxy := float64(C.Dinucleotides[i][j]) / float64(pairs)
+	fx := float64(C.Bases[i]) / float64(bases)
+	fy := float64(C.Bases[j]) / float64(bases)
+	return fxy / (fx * fy)
+}
+
+// WriteCodonUsage writes a TSV table with one line per codon per Composition: the
+// ID, codon, amino acid, count and RSCU
+func WriteCodonUsage(w io.Writer, comps []Composition, code GeneticCode) error {
+
+	c := csv.NewWriter(w)
+	c.Comma = '\t'
+	if err := c.Write([]string{"id", "codon", "amino_acid", "count", "rscu"}); err != nil {
+		return err
+	}
+
+	for i := range comps {
+		rscu := comps[i].RSCU(code)
+		for j := range code {
+			codon := string([]byte{ncbiBases[j/16], ncbiBases[j/4%4], ncbiBases[j%4]})
+			err := c.Write([]string{
+				comps[i].ID,
+				codon,
+				string(code[j]),
+				strconv.Itoa(comps[i].Codons[j]),
+				strconv.FormatFloat(rscu[j], 'f', 3, 64),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	c.Flush()
+	return c.Error()
+}
+
+// WriteDinucleotides writes a TSV table with one line per dinucleotide per
+// Composition: the ID, dinucleotide, count and odds ratio
+func WriteDinucleotides(w io.Writer, comps []Composition) error {
+
+	c := csv.NewWriter(w)
+	c.Comma = '\t'
+	if err := c.Write([]string{"id", "dinucleotide", "count", "odds_ratio"}); err != nil {
+		return err
+	}
+
+	for i := range comps {
+		for a := 0; a < 4; a++ {
+			for b := 0; b < 4; b++ {
+				err := c.Write([]string{
+					comps[i].ID,
+					string([]byte{acgt[a], acgt[b]}),
+					strconv.Itoa(comps[i].Dinucleotides[a][b]),
+					strconv.FormatFloat(comps[i].OddsRatio(acgt[a], acgt[b]), 'f', 3, 64),
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	c.Flush()
+	return c.Error()
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+)
+
+var errMixedEncoding = errors.New("Cannot combine encoded and unencoded records")
+
+// Where one input record lies in a concatenated record (0-based, half-open)
+type ConcatSegment struct {
+	ID    string
+	Start int
+	End   int
+}
+
+// Concat joins records into a single record (e.g. a pseudo-chromosome), separated
+// by spacer Ns, and returns it with the position of every input record within it.
+// The records must all be encoded or all unencoded. The new record has the ID
+// "concatenated" and can be renamed by the caller.
+func Concat(records []FastaRecord, spacer int) (FastaRecord, []ConcatSegment, error) {
+
+	joined := FastaRecord{ID: "concatenated", Description: "concatenated"}
+	segments := make([]ConcatSegment, 0, len(records))
+
+	if len(records) == 0 {
+		return joined, segments, nil
+	}
+	joined.encoded = records[0].encoded
+
+	total := 0
+	for _, FR := range records {
+		if FR.encoded != joined.encoded {
+			return FastaRecord{}, []ConcatSegment{}, errMixedEncoding
+		}
+		total += len(FR.Seq) + spacer
+	}
+
+	var n byte = 'N'
+	if joined.encoded {
+		n = 240
+	}
+
+	joined.Seq = make([]byte, 0, total)
+	for i, FR := range records {
+		if i > 0 {
+			for j := 0; j < spacer; j++ {
+				joined.Seq = append(joined.Seq, n)
+			}
+		}
+		start := len(joined.Seq)
+		joined.Seq = append(joined.Seq, FR.Seq...)
+		segments = append(segments, ConcatSegment{ID: FR.ID, Start: start, End: len(joined.Seq)})
+	}
+
+	return joined, segments, nil
+}
+
+// SplitSegments is the inverse of Concat: it cuts the segments back out of a
+// concatenated record (dropping the spacers), naming each one by its segment ID
+func (FR *FastaRecord) SplitSegments(segments []ConcatSegment) ([]FastaRecord, error) {
+	pieces := make([]FastaRecord, 0, len(segments))
+	for _, s := range segments {
+		if s.Start < 0 || s.End > len(FR.Seq) || s.Start > s.End {
+			return []FastaRecord{}, errSeqBounds
+		}
+		seq := make([]byte, s.End-s.Start)
+		copy(seq, FR.Seq[s.Start:s.End])
+		pieces = append(pieces, FastaRecord{ID: s.ID, Description: s.ID, Seq: seq, Idx: len(pieces), encoded: FR.encoded})
+	}
+	return pieces, nil
+}
+
+// SplitAt cuts a record at the given 0-based positions, returning the pieces named
+// <ID>_1, <ID>_2, ... Each position becomes the first base of a new piece.
+func (FR *FastaRecord) SplitAt(positions []int) ([]FastaRecord, error) {
+
+	cuts := make([]int, len(positions))
+	copy(cuts, positions)
+	sort.Ints(cuts)
+
+	segments := make([]ConcatSegment, 0, len(cuts)+1)
+	prev := 0
+	for _, c := range append(cuts, len(FR.Seq)) {
+		if c < 0 || c > len(FR.Seq) {
+			return []FastaRecord{}, errSeqBounds
+		}
+		if c == prev {
+			continue
+		}
+		segments = append(segments, ConcatSegment{ID: FR.ID + "_" + strconv.Itoa(len(segments)+1), Start: prev, End: c})
+		prev = c
+	}
+
+	return FR.SplitSegments(segments)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"io"
+	"math/rand"
+	"sort"
+)
+
+// How ties between equally common bases are broken when building a consensus
+type TieBreak int
+
+const (
+	TieDeterministic TieBreak = iota // prefer A, then C, then G, then T
+	TieRandom                        // choose at random, using ConsensusOptions.Seed
+)
+
+// Options for Consensus
+type ConsensusOptions struct {
+	// If the most common base has a frequency below Threshold, the consensus is the
+	// IUPAC code for the smallest set of bases whose combined frequency reaches it.
+	// 0 gives a simple majority-rule consensus.
+	Threshold float64
+	TieBreak  TieBreak
+	Seed      int64
+}
+
+// The consensus call at one alignment column
+type ConsensusSite struct {
+	Pos     int
+	Base    byte    // the decoded consensus base
+	Support float64 // the combined frequency of the bases represented by Base
+}
+
+// the encoding bits for A, C, G and T, in the order used by SiteFrequencies
+var baseBits = [4]byte{128, 32, 64, 16}
+
+// Consensus builds a consensus sequence from an alignment (encoded or not), from
+// the unambiguous bases in each column. A column with no unambiguous bases is
+// called as a gap if most records have a gap there, and N otherwise. The consensus
+// record is encoded if the first record is, and the per-site calls are returned
+// alongside it.
+func Consensus(records []FastaRecord, opts ConsensusOptions) (FastaRecord, []ConsensusSite, error) {
+
+	sites, err := AlleleFrequencies(records)
+	if err != nil {
+		return FastaRecord{}, []ConsensusSite{}, err
+	}
+
+	counts := make([]ColumnCounts, len(sites))
+	for i, SF := range sites {
+		copy(counts[i][:4], SF.Counts[:])
+	}
+	for _, FR := range records {
+		for i := range FR.Seq {
+			if FR.encodedAt(i) == 244 {
+				counts[i][CountGap]++
+			}
+		}
+	}
+
+	encoded := len(records) > 0 && records[0].encoded
+	FR, calls := consensusFromCounts(counts, len(records), encoded, opts)
+
+	return FR, calls, nil
+}
+
+// consensusFromCounts calls the consensus at each column from its counts, where
+// records is the number of records in the alignment
+func consensusFromCounts(counts []ColumnCounts, records int, encoded bool, opts ConsensusOptions) (FastaRecord, []ConsensusSite) {
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	calls := make([]ConsensusSite, len(counts))
+	seq := make([]byte, len(counts))
+
+	for i, CC := range counts {
+		calls[i].Pos = i
+
+		n := CC.Bases()
+		if n == 0 {
+			calls[i].Base = 'N'
+			if 2*CC[CountGap] > records {
+				calls[i].Base = '-'
+			}
+			seq[i] = calls[i].Base
+			continue
+		}
+
+		order := []int{0, 1, 2, 3}
+		if opts.TieBreak == TieRandom {
+			rng.Shuffle(len(order), func(a, b int) { order[a], order[b] = order[b], order[a] })
+		}
+		sort.SliceStable(order, func(a, b int) bool { return CC[order[a]] > CC[order[b]] })
+
+		var e byte
+		taken, cumulative := 0, 0.0
+		for _, b := range order {
+			if CC[b] == 0 || (taken > 0 && cumulative >= opts.Threshold) {
+				break
+			}
+			e |= baseBits[b]
+			taken++
+			cumulative += float64(CC[b]) / float64(n)
+		}
+		if taken == 1 {
+			e |= 8
+		}
+
+		calls[i].Base = decodingArray[e]
+		calls[i].Support = cumulative
+		seq[i] = calls[i].Base
+	}
+
+	FR := FastaRecord{ID: "consensus", Description: "consensus", Seq: seq}
+	if encoded {
+		FR.MustEncode()
+	}
+
+	return FR, calls
+}
+
+// StreamConsensus is Consensus for an alignment read from r, which is counted one
+// record at a time rather than loaded into memory. It makes the same calls as
+// Consensus, except that any character that isn't a nucleotide is an error.
+func StreamConsensus(r io.Reader, opts ConsensusOptions) (FastaRecord, []ConsensusSite, error) {
+	CC, err := countStream(r)
+	if err != nil {
+		return FastaRecord{}, []ConsensusSite{}, err
+	}
+	FR, calls := consensusFromCounts(CC.Counts(), CC.Records(), CC.encoded, opts)
+	return FR, calls, nil
+}
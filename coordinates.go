@@ -0,0 +1,84 @@
+package main
+
+import "errors"
+
+var errRecordNotFound = errors.New("Record not found in alignment")
+
+// A CoordinateMap converts between alignment columns and positions in the
+// (ungapped) reference sequence. All positions are 0-based.
+type CoordinateMap struct {
+	refToAln []int // the alignment column of each reference base
+	alnToRef []int // the reference position of each column, or -1 where the reference has a gap
+}
+
+// NewCoordinateMap builds a CoordinateMap from the gapped reference record in an
+// alignment, which may be encoded or not
+func NewCoordinateMap(ref FastaRecord) *CoordinateMap {
+	CM := &CoordinateMap{
+		refToAln: make([]int, 0, len(ref.Seq)),
+		alnToRef: make([]int, len(ref.Seq)),
+	}
+	for i := range ref.Seq {
+		if ref.encodedAt(i) == 244 {
+			CM.alnToRef[i] = -1
+			continue
+		}
+		CM.alnToRef[i] = len(CM.refToAln)
+		CM.refToAln = append(CM.refToAln, i)
+	}
+	return CM
+}
+
+// CoordinateMapFromAlignment finds the record with the given ID in an alignment and
+// builds a CoordinateMap from it
+func CoordinateMapFromAlignment(records []FastaRecord, refID string) (*CoordinateMap, error) {
+	for _, FR := range records {
+		if FR.ID == refID {
+			return NewCoordinateMap(FR), nil
+		}
+	}
+	return nil, errRecordNotFound
+}
+
+// ToReference returns the reference position of an alignment column. ok is false
+// if the column is out of range or the reference has a gap there.
+func (CM *CoordinateMap) ToReference(alnPos int) (refPos int, ok bool) {
+	if alnPos < 0 || alnPos >= len(CM.alnToRef) || CM.alnToRef[alnPos] == -1 {
+		return -1, false
+	}
+	return CM.alnToRef[alnPos], true
+}
+
+// ToAlignment returns the alignment column of a reference position. ok is false if
+// the position is out of range, or (for a map from ApplyMutations) it was deleted.
+func (CM *CoordinateMap) ToAlignment(refPos int) (alnPos int, ok bool) {
+	if refPos < 0 || refPos >= len(CM.refToAln) || CM.refToAln[refPos] == -1 {
+		return -1, false
+	}
+	return CM.refToAln[refPos], true
+}
+
+// PrecedingReference returns the reference position of the last reference base at or
+// before an alignment column, or -1 if there isn't one (e.g. for an insertion before
+// the start of the reference)
+func (CM *CoordinateMap) PrecedingReference(alnPos int) int {
+	if alnPos >= len(CM.alnToRef) {
+		alnPos = len(CM.alnToRef) - 1
+	}
+	for ; alnPos >= 0; alnPos-- {
+		if CM.alnToRef[alnPos] != -1 {
+			return CM.alnToRef[alnPos]
+		}
+	}
+	return -1
+}
+
+// ReferenceLength is the number of (ungapped) bases in the reference
+func (CM *CoordinateMap) ReferenceLength() int {
+	return len(CM.refToAln)
+}
+
+// AlignmentWidth is the number of columns in the alignment
+func (CM *CoordinateMap) AlignmentWidth() int {
+	return len(CM.alnToRef)
+}
@@ -0,0 +1,46 @@
+package main
+
+// SeqView returns the record's sequence without copying it, so it aliases FR.Seq:
+// changes to one show up in the other, and the view is only valid for as long as the
+// buffer is (for instance, until the record is given back with PutRecord or written
+// by a Writer with Recycle set). Its capacity is limited to its length, so appending
+// to it allocates rather than writing over whatever follows in the buffer.
+func (FR *FastaRecord) SeqView() []byte {
+	return FR.Seq[:len(FR.Seq):len(FR.Seq)]
+}
+
+// SeqCopy returns a copy of the record's sequence that shares no memory with it
+func (FR *FastaRecord) SeqCopy() []byte {
+	seq := make([]byte, len(FR.Seq))
+	copy(seq, FR.Seq)
+	return seq
+}
+
+// Detach gives the record its own copies of its sequence and annotations, so that
+// it stays valid after the buffers it was read into are reused
+func (FR *FastaRecord) Detach() {
+	FR.Seq = FR.SeqCopy()
+	FR.Annotations = copyAnnotations(FR.Annotations)
+}
+
+// CloneAlignment returns a deep copy of every record in aln
+func CloneAlignment(aln []FastaRecord) []FastaRecord {
+	c := make([]FastaRecord, len(aln))
+	for i := range aln {
+		c[i] = aln[i].Clone()
+	}
+	return c
+}
+
+// copyAnnotations returns a new map with the same entries. The values themselves
+// are not copied.
+func copyAnnotations(annotations map[string]any) map[string]any {
+	if annotations == nil {
+		return nil
+	}
+	c := make(map[string]any, len(annotations))
+	for k, v := range annotations {
+		c[k] = v
+	}
+	return c
+}
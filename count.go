@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"io"
+)
+
+// CountRecords counts the records in fasta read from r, by counting the lines that
+// start with '>', without parsing the records or allocating per record
+func CountRecords(r io.Reader) (int, error) {
+	records, _, err := CountRecordsAndBases(r)
+	return records, err
+}
+
+// TotalBases counts the sequence bytes in fasta read from r (everything on lines
+// that aren't headers, except line endings), without parsing the records
+func TotalBases(r io.Reader) (int64, error) {
+	_, bases, err := CountRecordsAndBases(r)
+	return bases, err
+}
+
+// CountRecordsAndBases does the work of CountRecords and TotalBases in one pass
+func CountRecordsAndBases(r io.Reader) (records int, bases int64, err error) {
+	// line endings are converted the same way as by Reader
+	r = &newlineReader{r: r}
+	buf := make([]byte, 1<<16)
+	lineStart := true // whether the next byte starts a line
+	header := false   // whether the current line is a header
+
+	for {
+		n, err := r.Read(buf)
+		chunk := buf[:n]
+		for len(chunk) > 0 {
+			if lineStart {
+				header = chunk[0] == '>'
+				if header {
+					records++
+				}
+			}
+			line := chunk
+			i := bytes.IndexByte(chunk, '\n')
+			if i >= 0 {
+				line = chunk[:i]
+				chunk = chunk[i+1:]
+			} else {
+				chunk = nil
+			}
+			lineStart = i >= 0
+			if !header {
+				bases += int64(len(line))
+			}
+		}
+		if err == io.EOF {
+			return records, bases, nil
+		} else if err != nil {
+			return records, bases, err
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import "io"
+
+// The classes counted by CountMatrix, which index a ColumnCounts
+const (
+	CountA = iota
+	CountC
+	CountG
+	CountT
+	CountN     // N and '?'
+	CountGap   // '-'
+	CountAmbig // any other IUPAC ambiguity code
+	numCountClasses
+)
+
+// The number of each class of character in one alignment column
+type ColumnCounts [numCountClasses]int
+
+// Total returns the number of records counted in the column
+func (CC ColumnCounts) Total() int {
+	n := 0
+	for _, c := range CC {
+		n += c
+	}
+	return n
+}
+
+// Bases returns the number of unambiguous bases in the column
+func (CC ColumnCounts) Bases() int {
+	return CC[CountA] + CC[CountC] + CC[CountG] + CC[CountT]
+}
+
+// noCountClass marks bytes that aren't nucleotides in the lookup tables: the high bit
+// flags them, and the low bits are a valid index so that counting them is harmless
+const noCountClass = 128 | CountAmbig
+
+var countClassesEncoded, countClassesRaw = makeCountClasses()
+
+// makeCountClasses builds the tables from an encoded byte, and from an unencoded
+// byte, to its class
+func makeCountClasses() (encoded [256]byte, raw [256]byte) {
+	for i := range encoded {
+		encoded[i] = noCountClass
+	}
+	for _, E := range encodingTable {
+		switch {
+		case E.code&8 != 0:
+			encoded[E.code] = byte(baseIndex(E.code))
+		case E.code == 240 || E.code == 242:
+			encoded[E.code] = CountN
+		case E.code == 244:
+			encoded[E.code] = CountGap
+		default:
+			encoded[E.code] = CountAmbig
+		}
+	}
+	for i := range raw {
+		raw[i] = noCountClass
+		if e := encodingArray[i]; e != 0 {
+			raw[i] = encoded[e]
+		}
+	}
+	return encoded, raw
+}
+
+// A ColumnCounter accumulates the counts of an alignment's columns one record at a
+// time, so that the alignment never has to be held in memory
+type ColumnCounter struct {
+	counts  []ColumnCounts
+	records int
+	encoded bool // whether the first record was encoded
+}
+
+// Add counts the characters of one record (encoded or not), which must be the same
+// length as the others. errInvalidNucleotide is returned if it has a character that
+// isn't a nucleotide, in which case the counts are left as they were.
+func (CC *ColumnCounter) Add(FR FastaRecord) error {
+
+	if CC.records == 0 {
+		CC.counts = make([]ColumnCounts, len(FR.Seq))
+		CC.encoded = FR.encoded
+	} else if len(FR.Seq) != len(CC.counts) {
+		return errDifferentWidths
+	}
+
+	table := &countClassesRaw
+	if FR.encoded {
+		table = &countClassesEncoded
+	}
+
+	// a table lookup per byte, with no branches in the inner loop; invalid bytes are
+	// only looked for once the record has been counted
+	var bad byte
+	for j, b := range FR.Seq {
+		class := table[b]
+		bad |= class & 128
+		CC.counts[j][class&^128]++
+	}
+
+	if bad != 0 {
+		for j, b := range FR.Seq {
+			CC.counts[j][table[b]&^128]--
+		}
+		return errInvalidNucleotide
+	}
+
+	CC.records++
+	return nil
+}
+
+// Records returns the number of records counted so far
+func (CC *ColumnCounter) Records() int {
+	return CC.records
+}
+
+// Counts returns the counts for each column. The slice is the counter's own, and
+// changes if more records are added.
+func (CC *ColumnCounter) Counts() []ColumnCounts {
+	if CC.counts == nil {
+		return []ColumnCounts{}
+	}
+	return CC.counts
+}
+
+// CountMatrix counts the bases, Ns, gaps and other ambiguity codes in every column of
+// an alignment (encoded or not). The records must all be the same length, and
+// errInvalidNucleotide is returned if any has a character that isn't a nucleotide.
+func CountMatrix(aln []FastaRecord) ([]ColumnCounts, error) {
+	var CC ColumnCounter
+	for _, FR := range aln {
+		if err := CC.Add(FR); err != nil {
+			return []ColumnCounts{}, err
+		}
+	}
+	return CC.Counts(), nil
+}
+
+// StreamCountMatrix is CountMatrix for an alignment read from r, one record at a time
+func StreamCountMatrix(r io.Reader) ([]ColumnCounts, error) {
+	CC, err := countStream(r)
+	if err != nil {
+		return []ColumnCounts{}, err
+	}
+	return CC.Counts(), nil
+}
+
+// countStream counts every record that can be read from r
+func countStream(r io.Reader) (ColumnCounter, error) {
+	var CC ColumnCounter
+	reader := NewReader(r)
+	for {
+		FR, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return ColumnCounter{}, err
+		}
+		if err = CC.Add(FR); err != nil {
+			return ColumnCounter{}, err
+		}
+	}
+	return CC, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"io"
+)
+
+// A Deduplicator drops records whose sequence it has already seen, keeping only a
+// SHA-256 hash of each distinct sequence (and the ID of the record that had it),
+// so memory use depends on the number of distinct sequences and not their length.
+// Sequences are compared as uppercase, decoded text, so case and encoding don't
+// matter.
+type Deduplicator struct {
+	seen    map[[sha256.Size]byte]string
+	mapping *csv.Writer
+	Kept    int // the number of records with a sequence not seen before
+	Dropped int // the number of duplicates
+}
+
+// NewDeduplicator returns an empty Deduplicator. If mapping isn't nil, a tsv of
+// each dropped record's ID and the ID of the record it duplicates is written to it
+// (call Flush once finished).
+func NewDeduplicator(mapping io.Writer) (*Deduplicator, error) {
+	D := &Deduplicator{seen: make(map[[sha256.Size]byte]string)}
+	if mapping != nil {
+		D.mapping = csv.NewWriter(mapping)
+		D.mapping.Comma = '\t'
+		if err := D.mapping.Write([]string{"id", "duplicate_of"}); err != nil {
+			return nil, err
+		}
+	}
+	return D, nil
+}
+
+// seqHash returns the SHA-256 of the record's uppercase, decoded sequence
+func seqHash(FR *FastaRecord) [sha256.Size]byte {
+	h := sha256.New()
+	var buf [4096]byte
+	for start := 0; start < len(FR.Seq); start += len(buf) {
+		n := 0
+		for i := start; i < len(FR.Seq) && n < len(buf); i++ {
+			buf[n] = FR.residue(i)
+			n++
+		}
+		h.Write(buf[:n])
+	}
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// Transform returns ErrSkipRecord if the record's sequence has been seen before, so
+// that it can be passed to WithTransform to deduplicate records as they are read
+func (D *Deduplicator) Transform(FR *FastaRecord) error {
+	sum := seqHash(FR)
+	first, ok := D.seen[sum]
+	if !ok {
+		D.seen[sum] = FR.ID
+		D.Kept++
+		return nil
+	}
+	D.Dropped++
+	if D.mapping != nil {
+		if err := D.mapping.Write([]string{FR.ID, first}); err != nil {
+			return err
+		}
+	}
+	return ErrSkipRecord
+}
+
+// Flush writes any buffered mapping lines
+func (D *Deduplicator) Flush() error {
+	if D.mapping == nil {
+		return nil
+	}
+	D.mapping.Flush()
+	return D.mapping.Error()
+}
+
+// Dedup copies fasta from r to w in one pass, dropping every record whose sequence
+// has already been seen, and writes the mapping of dropped to kept IDs to mapping
+// if it isn't nil
+func Dedup(r io.Reader, w io.Writer, mapping io.Writer) (*Deduplicator, error) {
+	D, err := NewDeduplicator(mapping)
+	if err != nil {
+		return nil, err
+	}
+	reader := NewReader(r, WithTransform(D.Transform))
+	writer := NewWriter(w)
+	for {
+		FR, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return D, err
+		}
+		if err = writer.Write(FR); err != nil {
+			return D, err
+		}
+	}
+	if err = writer.Flush(); err != nil {
+		return D, err
+	}
+	return D, D.Flush()
+}
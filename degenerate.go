@@ -0,0 +1,139 @@
+package main
+
+// The degeneracy of one coding site of the reference
+type DegenerateSite struct {
+	Gene   string
+	RefPos int // 1-based reference position, as for Gene
+	Column int // 0-based alignment column
+	// 0, 2 or 4: whether no, some or all changes at the site are synonymous. Sites
+	// where three of the four bases are synonymous (e.g. the third position of Ile
+	// codons) are counted as 2-fold.
+	Fold int
+}
+
+// the index in NCBI table order (T, C, A, G) of an unambiguous encoded base, or -1
+func ncbiIndex(e byte) int {
+	switch e {
+	case 24:
+		return 0
+	case 40:
+		return 1
+	case 136:
+		return 2
+	case 72:
+		return 3
+	}
+	return -1
+}
+
+// codonFolds returns the degeneracy of each position of a codon given as NCBI
+// indices
+func (GC *GeneticCode) codonFolds(codon [3]int) [3]int {
+	var folds [3]int
+	aa := GC[codon[0]*16+codon[1]*4+codon[2]]
+	for p := 0; p < 3; p++ {
+		synonymous := 0
+		alt := codon
+		for b := 0; b < 4; b++ {
+			alt[p] = b
+			if GC[alt[0]*16+alt[1]*4+alt[2]] == aa {
+				synonymous++
+			}
+		}
+		switch synonymous {
+		case 1:
+			folds[p] = 0
+		case 4:
+			folds[p] = 4
+		default:
+			folds[p] = 2
+		}
+	}
+	return folds
+}
+
+// ClassifySites works out the degeneracy of every site in genes from the
+// reference's codons, under the given genetic code. The reference may be aligned
+// (gaps are skipped, and the alignment column of each site is reported). Codons
+// with anything other than A, C, G or T in the reference are skipped, as is any
+// incomplete codon at the end of a gene.
+func ClassifySites(ref FastaRecord, genes []Gene, code GeneticCode) ([]DegenerateSite, error) {
+
+	CM := NewCoordinateMap(ref)
+	for _, g := range genes {
+		if g.Start < 1 || g.End < g.Start || g.End > CM.ReferenceLength() {
+			return []DegenerateSite{}, errGeneBounds
+		}
+	}
+
+	sites := make([]DegenerateSite, 0)
+
+	for _, g := range genes {
+	codons:
+		for start := g.Start - 1; start+2 <= g.End-1; start += 3 {
+			var (
+				codon   [3]int
+				columns [3]int
+			)
+			for p := 0; p < 3; p++ {
+				columns[p], _ = CM.ToAlignment(start + p)
+				codon[p] = ncbiIndex(ref.encodedAt(columns[p]))
+				if codon[p] < 0 {
+					continue codons
+				}
+			}
+			folds := code.codonFolds(codon)
+			for p := 0; p < 3; p++ {
+				sites = append(sites, DegenerateSite{Gene: g.Name, RefPos: start + p + 1, Column: columns[p], Fold: folds[p]})
+			}
+		}
+	}
+
+	return sites, nil
+}
+
+// FourFoldSites extracts the alignment columns that are 4-fold degenerate in the
+// reference record refID (see ClassifySites), for neutral-rate analyses. A column in
+// more than one gene is only kept if it is 4-fold in all of them. It returns copies
+// of the records with only those columns, and the (0-based, ascending) columns kept.
+func FourFoldSites(records []FastaRecord, refID string, genes []Gene, code GeneticCode) ([]FastaRecord, []int, error) {
+
+	refIdx := -1
+	for i := range records {
+		if records[i].ID == refID {
+			refIdx = i
+			break
+		}
+	}
+	if refIdx == -1 {
+		return []FastaRecord{}, []int{}, errRecordNotFound
+	}
+	for _, FR := range records {
+		if len(FR.Seq) != len(records[refIdx].Seq) {
+			return []FastaRecord{}, []int{}, errDifferentWidths
+		}
+	}
+
+	sites, err := ClassifySites(records[refIdx], genes, code)
+	if err != nil {
+		return []FastaRecord{}, []int{}, err
+	}
+
+	fourFold := make(map[int]bool)
+	for _, s := range sites {
+		if ok, seen := fourFold[s.Column]; seen {
+			fourFold[s.Column] = ok && s.Fold == 4
+		} else {
+			fourFold[s.Column] = s.Fold == 4
+		}
+	}
+
+	kept := make([]int, 0, len(fourFold))
+	for c := range records[refIdx].Seq {
+		if fourFold[c] {
+			kept = append(kept, c)
+		}
+	}
+
+	return selectColumns(records, kept), kept, nil
+}
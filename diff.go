@@ -0,0 +1,130 @@
+package main
+
+// The kinds of difference reported by Diff
+type DiffType int
+
+const (
+	DiffSubstitution DiffType = iota // two different unambiguous bases
+	DiffInsertion                    // a run of gaps in a where b has sequence
+	DiffDeletion                     // a run of gaps in b where a has sequence
+	DiffAmbiguous                    // differing bases where at least one is an ambiguity code other than N
+)
+
+func (DT DiffType) String() string {
+	switch DT {
+	case DiffSubstitution:
+		return "substitution"
+	case DiffInsertion:
+		return "insertion"
+	case DiffDeletion:
+		return "deletion"
+	case DiffAmbiguous:
+		return "ambiguous"
+	}
+	return "unknown"
+}
+
+// One difference between two aligned records. Start is the 0-based alignment
+// position of the first site, and Length is the number of alignment columns.
+type Difference struct {
+	Type       DiffType
+	Start      int
+	Length     int
+	A          string // the bases in the first record
+	B          string // the bases in the second record
+	Compatible bool   // for ambiguous differences, whether the two codes share a possible base
+}
+
+// Diff compares two records of the same width (encoded or not) column by column.
+// Substitutions and ambiguous differences are reported per site, and runs of gaps
+// in one record but not the other are reported as a single insertion or deletion
+// (relative to a). Sites where either record has N or ? are treated as missing
+// data and are not reported, nor are sites where both records have a gap, so a gap
+// run is trimmed to the bases it removes or adds: a deletion never starts or ends
+// opposite an N in a, and an insertion of nothing but Ns is not reported at all.
+func Diff(a, b FastaRecord) ([]Difference, error) {
+	return DiffSequences(a.Sequence(), b.Sequence())
+}
+
+// DiffSequences is Diff for any two Sequences of the same length
+func DiffSequences(a, b Sequence) ([]Difference, error) {
+
+	if a.Len() != b.Len() {
+		return []Difference{}, errDifferentWidths
+	}
+	va, vb := viewOf(a), viewOf(b)
+
+	diffs := make([]Difference, 0)
+
+	for i := 0; i < va.n; i++ {
+		ea, eb := va.at(i), vb.at(i)
+		if ea == eb {
+			continue
+		}
+
+		// gap runs
+		if ea == 244 || eb == 244 {
+			if ea == 244 && eb == 244 {
+				continue
+			}
+			DT := DiffDeletion
+			if ea == 244 {
+				DT = DiffInsertion
+			}
+			j := i + 1
+			for ; j < va.n; j++ {
+				ga, gb := va.at(j) == 244, vb.at(j) == 244
+				if (DT == DiffInsertion && !(ga && !gb)) || (DT == DiffDeletion && !(gb && !ga)) {
+					break
+				}
+			}
+			// the ends of the run where the other record has N or ? are missing data
+			seq := va
+			if DT == DiffInsertion {
+				seq = vb
+			}
+			start, end := i, j
+			for start < end && isN(seq.at(start)) {
+				start++
+			}
+			for end > start && isN(seq.at(end-1)) {
+				end--
+			}
+			if end > start {
+				diffs = append(diffs, Difference{
+					Type:   DT,
+					Start:  start,
+					Length: end - start,
+					A:      va.decodeRange(start, end),
+					B:      vb.decodeRange(start, end),
+				})
+			}
+			i = j - 1
+			continue
+		}
+
+		if isN(ea) || isN(eb) {
+			continue
+		}
+
+		D := Difference{Start: i, Length: 1, A: va.decodeRange(i, i+1), B: vb.decodeRange(i, i+1)}
+		if ea&8 == 8 && eb&8 == 8 {
+			D.Type = DiffSubstitution
+		} else {
+			D.Type = DiffAmbiguous
+			D.Compatible = ea&eb >= 16
+		}
+		diffs = append(diffs, D)
+	}
+
+	return diffs, nil
+}
+
+// decodeRange returns the uppercase, decoded bases [start, end)
+func (v *encodedView) decodeRange(start, end int) string {
+	b := make([]byte, end-start)
+	for i := start; i < end; i++ {
+		b[i-start] = decodingArray[v.at(i)]
+	}
+	return string(b)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"sync"
+)
+
+// How the distance between two aligned records is measured. Only sites where both
+// records have an unambiguous base are compared.
+type DistanceMeasure int
+
+const (
+	DistanceSNP  DistanceMeasure = iota // the number of sites that differ
+	DistanceP                           // the proportion of compared sites that differ
+	DistanceJC69                        // the Jukes-Cantor (1969) corrected distance, +Inf if saturated
+)
+
+var errUnknownDistance = errors.New("Unknown distance measure")
+
+// A symmetric matrix of pairwise distances between records
+type DistanceMatrix struct {
+	IDs []string
+	D   [][]float64
+}
+
+// pairDifferences counts the sites where a and b both have an unambiguous base, and
+// the number of those where the bases differ
+func pairDifferences(a, b *FastaRecord) (compared, differences int) {
+	return sequenceDifferences(viewOf(a.Sequence()), viewOf(b.Sequence()))
+}
+
+func sequenceDifferences(a, b encodedView) (compared, differences int) {
+	for i := 0; i < a.n; i++ {
+		x, y := a.at(i), b.at(i)
+		if x&8 == 8 && y&8 == 8 {
+			compared++
+			if x != y {
+				differences++
+			}
+		}
+	}
+	return compared, differences
+}
+
+func distanceFrom(compared, differences int, measure DistanceMeasure) float64 {
+	switch measure {
+	case DistanceSNP:
+		return float64(differences)
+	case DistanceP:
+		if compared == 0 {
+			return math.NaN()
+		}
+		return float64(differences) / float64(compared)
+	default:
+		if compared == 0 {
+			return math.NaN()
+		}
+		p := float64(differences) / float64(compared)
+		if p >= 0.75 {
+			return math.Inf(1)
+		}
+		return -0.75 * math.Log(1-4*p/3)
+	}
+}
+
+// SequenceDistance is the distance between two Sequences of the same length, and
+// the number of sites compared
+func SequenceDistance(a, b Sequence, measure DistanceMeasure) (float64, int, error) {
+	if measure < DistanceSNP || measure > DistanceJC69 {
+		return 0, 0, errUnknownDistance
+	}
+	if a.Len() != b.Len() {
+		return 0, 0, errDifferentWidths
+	}
+	compared, differences := sequenceDifferences(viewOf(a), viewOf(b))
+	return distanceFrom(compared, differences, measure), compared, nil
+}
+
+// Distances computes the distance between every pair of records in an alignment
+// (encoded or not), using up to threads goroutines. Pairs with no sites to compare
+// get NaN for the proportional measures.
+func Distances(aln []FastaRecord, measure DistanceMeasure, threads int) (*DistanceMatrix, error) {
+
+	if measure < DistanceSNP || measure > DistanceJC69 {
+		return nil, errUnknownDistance
+	}
+	for _, FR := range aln {
+		if len(FR.Seq) != len(aln[0].Seq) {
+			return nil, errDifferentWidths
+		}
+	}
+	if threads < 1 {
+		threads = 1
+	}
+
+	n := len(aln)
+	DM := &DistanceMatrix{IDs: make([]string, n), D: make([][]float64, n)}
+	for i := range aln {
+		DM.IDs[i] = aln[i].ID
+		DM.D[i] = make([]float64, n)
+	}
+
+	var wg sync.WaitGroup
+	rows := make(chan int)
+
+	for t := 0; t < threads; t++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range rows {
+				for j := i + 1; j < n; j++ {
+					compared, differences := pairDifferences(&aln[i], &aln[j])
+					d := distanceFrom(compared, differences, measure)
+					// every goroutine writes to different elements
+					DM.D[i][j], DM.D[j][i] = d, d
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		rows <- i
+	}
+	close(rows)
+	wg.Wait()
+
+	return DM, nil
+}
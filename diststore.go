@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"sync"
+)
+
+var errBadlyFormedDistances = errors.New("Badly formed distance store")
+
+// the first bytes of a saved DistanceStore
+const distanceStoreMagic = "fastaigo-dist-1\n"
+
+// A DistanceStore is a pairwise distance matrix that grows as records are added,
+// for datasets that get new sequences every day: adding records only computes their
+// distances to the records already there (and to each other), and the matrix can be
+// saved to disk between runs.
+type DistanceStore struct {
+	measure DistanceMeasure
+	ids     []string
+	index   map[string]int
+	rows    [][]float64 // the lower triangle: rows[i][j] for j < i
+}
+
+// NewDistanceStore returns an empty DistanceStore that will use the given measure
+func NewDistanceStore(measure DistanceMeasure) (*DistanceStore, error) {
+	if measure < DistanceSNP || measure > DistanceJC69 {
+		return nil, errUnknownDistance
+	}
+	return &DistanceStore{measure: measure, ids: make([]string, 0), index: make(map[string]int), rows: make([][]float64, 0)}, nil
+}
+
+// Add adds every record of aln whose ID is not already in the store, computing its
+// distances on threads goroutines. aln is usually the whole, current alignment: the
+// records already in the store must be in it too, since the new records are
+// compared against them (errRecordNotFound if one is missing). It returns the
+// number of records added.
+func (DS *DistanceStore) Add(aln []FastaRecord, threads int) (int, error) {
+
+	for _, FR := range aln {
+		if len(FR.Seq) != len(aln[0].Seq) {
+			return 0, errDifferentWidths
+		}
+	}
+	if threads < 1 {
+		threads = 1
+	}
+
+	byID := make(map[string]*FastaRecord, len(aln))
+	for i := range aln {
+		byID[aln[i].ID] = &aln[i]
+	}
+
+	// every record in store order, with the new ones at the end
+	records := make([]*FastaRecord, 0, len(aln))
+	for _, id := range DS.ids {
+		FR, ok := byID[id]
+		if !ok {
+			return 0, errRecordNotFound
+		}
+		records = append(records, FR)
+	}
+	old := len(records)
+	for i := range aln {
+		if _, ok := DS.index[aln[i].ID]; !ok {
+			DS.index[aln[i].ID] = len(records)
+			records = append(records, &aln[i])
+		}
+	}
+	for _, FR := range records[old:] {
+		DS.ids = append(DS.ids, FR.ID)
+	}
+
+	newRows := make([][]float64, len(records)-old)
+	var wg sync.WaitGroup
+	jobs := make(chan int)
+
+	for t := 0; t < threads; t++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				row := make([]float64, i)
+				for j := 0; j < i; j++ {
+					compared, differences := pairDifferences(records[i], records[j])
+					row[j] = distanceFrom(compared, differences, DS.measure)
+				}
+				newRows[i-old] = row
+			}
+		}()
+	}
+
+	for i := old; i < len(records); i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	DS.rows = append(DS.rows, newRows...)
+	return len(newRows), nil
+}
+
+// Len returns the number of records in the store
+func (DS *DistanceStore) Len() int {
+	return len(DS.ids)
+}
+
+// Distance returns the distance between two records
+func (DS *DistanceStore) Distance(a, b string) (float64, bool) {
+	i, okA := DS.index[a]
+	j, okB := DS.index[b]
+	if !okA || !okB {
+		return 0, false
+	}
+	switch {
+	case i == j:
+		return 0, true
+	case i < j:
+		i, j = j, i
+	}
+	return DS.rows[i][j], true
+}
+
+// Matrix returns the full distance matrix, with the records in the order they were
+// added
+func (DS *DistanceStore) Matrix() *DistanceMatrix {
+	n := len(DS.ids)
+	DM := &DistanceMatrix{IDs: make([]string, n), D: make([][]float64, n)}
+	copy(DM.IDs, DS.ids)
+	for i := range DM.D {
+		DM.D[i] = make([]float64, n)
+	}
+	for i, row := range DS.rows {
+		for j, d := range row {
+			DM.D[i][j], DM.D[j][i] = d, d
+		}
+	}
+	return DM
+}
+
+// Write saves the store to w, so that it can be loaded again with ReadDistanceStore
+func (DS *DistanceStore) Write(w io.Writer) error {
+
+	bw := bufio.NewWriter(w)
+	var buf [binary.MaxVarintLen64]byte
+
+	putUvarint := func(x uint64) {
+		n := binary.PutUvarint(buf[:], x)
+		bw.Write(buf[:n])
+	}
+
+	bw.WriteString(distanceStoreMagic)
+	putUvarint(uint64(DS.measure))
+	putUvarint(uint64(len(DS.ids)))
+	for _, id := range DS.ids {
+		putUvarint(uint64(len(id)))
+		bw.WriteString(id)
+	}
+	for _, row := range DS.rows {
+		for _, d := range row {
+			binary.LittleEndian.PutUint64(buf[:8], math.Float64bits(d))
+			bw.Write(buf[:8])
+		}
+	}
+
+	return bw.Flush()
+}
+
+// ReadDistanceStore loads a store saved by DistanceStore.Write
+func ReadDistanceStore(r io.Reader) (*DistanceStore, error) {
+
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(distanceStoreMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != distanceStoreMagic {
+		return nil, errBadlyFormedDistances
+	}
+
+	measure, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, errBadlyFormedDistances
+	}
+	DS, err := NewDistanceStore(DistanceMeasure(measure))
+	if err != nil {
+		return nil, errBadlyFormedDistances
+	}
+
+	n, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, errBadlyFormedDistances
+	}
+	for i := uint64(0); i < n; i++ {
+		id, _, err := readStoreBytes(br)
+		if err != nil {
+			return nil, errBadlyFormedDistances
+		}
+		DS.index[string(id)] = len(DS.ids)
+		DS.ids = append(DS.ids, string(id))
+	}
+
+	var buf [8]byte
+	for i := range DS.ids {
+		row := make([]float64, i)
+		for j := range row {
+			if _, err = io.ReadFull(br, buf[:]); err != nil {
+				return nil, errBadlyFormedDistances
+			}
+			row[j] = math.Float64frombits(binary.LittleEndian.Uint64(buf[:]))
+		}
+		DS.rows = append(DS.rows, row)
+	}
+
+	return DS, nil
+}
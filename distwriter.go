@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// formatDistance formats a distance compactly, keeping whole numbers (e.g. SNP
+// counts) as integers
+func formatDistance(d float64) string {
+	return strconv.FormatFloat(d, 'g', -1, 64)
+}
+
+// WritePhylip writes a distance matrix in PHYLIP format: the number of taxa, then a
+// row per taxon. In strict PHYLIP each name is truncated or padded to 10
+// characters; in relaxed PHYLIP it is written in full, followed by a space, so it
+// must not contain whitespace.
+func WritePhylip(w io.Writer, DM *DistanceMatrix, relaxed bool) error {
+
+	bw := bufio.NewWriter(w)
+	if _, err := fmt.Fprintf(bw, "%d\n", len(DM.IDs)); err != nil {
+		return err
+	}
+
+	for i, id := range DM.IDs {
+		if relaxed {
+			bw.WriteString(id)
+			bw.WriteByte(' ')
+		} else {
+			if len(id) > 10 {
+				id = id[:10]
+			}
+			fmt.Fprintf(bw, "%-10s", id)
+		}
+		for j, d := range DM.D[i] {
+			if j > 0 {
+				bw.WriteByte(' ')
+			}
+			bw.WriteString(formatDistance(d))
+		}
+		if err := bw.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// nexusLabel quotes a label if it contains anything other than letters, digits,
+// underscores, dots and dashes
+func nexusLabel(s string) string {
+	for _, c := range s {
+		if !(c == '_' || c == '.' || c == '-' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')) {
+			return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+		}
+	}
+	return s
+}
+
+// WriteNexusDistances writes a distance matrix as a Nexus file with TAXA and
+// DISTANCES blocks (the full square matrix, with labels)
+func WriteNexusDistances(w io.Writer, DM *DistanceMatrix) error {
+
+	bw := bufio.NewWriter(w)
+	n := len(DM.IDs)
+
+	labels := make([]string, n)
+	for i, id := range DM.IDs {
+		labels[i] = nexusLabel(id)
+	}
+
+	fmt.Fprintf(bw, "#NEXUS\n\nBEGIN TAXA;\n\tDIMENSIONS NTAX=%d;\n\tTAXLABELS %s;\nEND;\n\n", n, strings.Join(labels, " "))
+	fmt.Fprintf(bw, "BEGIN DISTANCES;\n\tDIMENSIONS NTAX=%d;\n\tFORMAT TRIANGLE=BOTH DIAGONAL LABELS;\n\tMATRIX\n", n)
+
+	for i := range DM.IDs {
+		bw.WriteString("\t" + labels[i])
+		for _, d := range DM.D[i] {
+			bw.WriteByte(' ')
+			bw.WriteString(formatDistance(d))
+		}
+		if err := bw.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+
+	bw.WriteString("\t;\nEND;\n")
+	return bw.Flush()
+}
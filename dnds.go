@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"strconv"
+)
+
+// The Nei-Gojobori estimate of synonymous and nonsynonymous divergence between two
+// codon-aligned records
+type DNDS struct {
+	ID1    string
+	ID2    string
+	Codons int     // the number of codons compared
+	S      float64 // synonymous sites
+	N      float64 // nonsynonymous sites
+	Sd     float64 // synonymous differences
+	Nd     float64 // nonsynonymous differences
+	PS     float64 // Sd / S
+	PN     float64 // Nd / N
+	DS     float64 // PS with the Jukes-Cantor correction (+Inf if PS >= 0.75)
+	DN     float64 // PN with the Jukes-Cantor correction (+Inf if PN >= 0.75)
+	Omega  float64 // DN / DS (NaN or +Inf if DS is 0)
+}
+
+// codonSites returns the number of synonymous sites in a codon (given as NCBI
+// indices): the fraction of the three possible changes at each position that don't
+// change the amino acid. Changes to stop codons count as nonsynonymous.
+func (GC *GeneticCode) codonSites(codon [3]int) float64 {
+	aa := GC[codon[0]*16+codon[1]*4+codon[2]]
+	s := 0.0
+	for p := 0; p < 3; p++ {
+		alt := codon
+		for b := 0; b < 4; b++ {
+			if b == codon[p] {
+				continue
+			}
+			alt[p] = b
+			if GC[alt[0]*16+alt[1]*4+alt[2]] == aa {
+				s += 1.0 / 3
+			}
+		}
+	}
+	return s
+}
+
+// codonDifferences returns the synonymous and nonsynonymous differences between two
+// codons, averaged over every order in which the differing positions could have
+// changed, leaving out orders that pass through a stop codon
+func (GC *GeneticCode) codonDifferences(a, b [3]int) (sd, nd float64) {
+	diff := make([]int, 0, 3)
+	for p := 0; p < 3; p++ {
+		if a[p] != b[p] {
+			diff = append(diff, p)
+		}
+	}
+	aaOf := func(c [3]int) byte { return GC[c[0]*16+c[1]*4+c[2]] }
+
+	paths := 0
+	var walk func(c [3]int, left []int, s, n float64)
+	walk = func(c [3]int, left []int, s, n float64) {
+		if len(left) == 0 {
+			sd += s
+			nd += n
+			paths++
+			return
+		}
+		for i, p := range left {
+			next := c
+			next[p] = b[p]
+			if aaOf(next) == '*' {
+				continue
+			}
+			rest := make([]int, 0, len(left)-1)
+			rest = append(rest, left[:i]...)
+			rest = append(rest, left[i+1:]...)
+			if aaOf(next) == aaOf(c) {
+				walk(next, rest, s+1, n)
+			} else {
+				walk(next, rest, s, n+1)
+			}
+		}
+	}
+	walk(a, diff, 0, 0)
+
+	if paths == 0 {
+		return 0, 0
+	}
+	return sd / float64(paths), nd / float64(paths)
+}
+
+// jukesCantor corrects a proportion of differences for multiple hits
+func jukesCantor(p float64) float64 {
+	if p == 0 {
+		return 0
+	} else if p >= 0.75 {
+		return math.Inf(1)
+	}
+	return -0.75 * math.Log(1-4*p/3)
+}
+
+// NeiGojobori estimates dN/dS between two codon-aligned records (encoded or not)
+// with the Nei-Gojobori (1986) counting method under the given genetic code. The
+// records are read in codons from their first base. Codons with anything other than
+// A, C, G or T (gaps, Ns and other ambiguity codes) in either record are left out,
+// as are codons that are a stop in either.
+func NeiGojobori(a, b FastaRecord, code GeneticCode) (DNDS, error) {
+	if len(a.Seq) != len(b.Seq) {
+		return DNDS{}, errDifferentWidths
+	}
+
+	R := DNDS{ID1: a.ID, ID2: b.ID}
+codons:
+	for start := 0; start+2 < len(a.Seq); start += 3 {
+		var ca, cb [3]int
+		for p := 0; p < 3; p++ {
+			ca[p] = ncbiIndex(a.encodedAt(start + p))
+			cb[p] = ncbiIndex(b.encodedAt(start + p))
+			if ca[p] < 0 || cb[p] < 0 {
+				continue codons
+			}
+		}
+		if code[ca[0]*16+ca[1]*4+ca[2]] == '*' || code[cb[0]*16+cb[1]*4+cb[2]] == '*' {
+			continue
+		}
+		R.Codons++
+		R.S += (code.codonSites(ca) + code.codonSites(cb)) / 2
+		sd, nd := code.codonDifferences(ca, cb)
+		R.Sd += sd
+		R.Nd += nd
+	}
+	R.N = 3*float64(R.Codons) - R.S
+
+	if R.S > 0 {
+		R.PS = R.Sd / R.S
+	}
+	if R.N > 0 {
+		R.PN = R.Nd / R.N
+	}
+	R.DS = jukesCantor(R.PS)
+	R.DN = jukesCantor(R.PN)
+	R.Omega = R.DN / R.DS
+	return R, nil
+}
+
+// PairwiseDNDS runs NeiGojobori on every pair of records in a codon alignment, in
+// the order (0, 1), (0, 2), ... (1, 2), ...
+func PairwiseDNDS(aln []FastaRecord, code GeneticCode) ([]DNDS, error) {
+	results := make([]DNDS, 0, len(aln)*(len(aln)-1)/2)
+	for i := range aln {
+		for j := i + 1; j < len(aln); j++ {
+			R, err := NeiGojobori(aln[i], aln[j], code)
+			if err != nil {
+				return []DNDS{}, err
+			}
+			results = append(results, R)
+		}
+	}
+	return results, nil
+}
+
+// DNDSMatrix arranges one value from each pairwise result (e.g. Omega or DS) in a
+// matrix over the records of aln, in the order that PairwiseDNDS returns them, so
+// that it can be written with WritePhylip or WriteNexusDistances
+func DNDSMatrix(aln []FastaRecord, results []DNDS, value func(DNDS) float64) DistanceMatrix {
+	DM := DistanceMatrix{IDs: make([]string, len(aln)), D: make([][]float64, len(aln))}
+	for i := range aln {
+		DM.IDs[i] = aln[i].ID
+		DM.D[i] = make([]float64, len(aln))
+	}
+	k := 0
+	for i := range aln {
+		for j := i + 1; j < len(aln) && k < len(results); j++ {
+			v := value(results[k])
+			DM.D[i][j], DM.D[j][i] = v, v
+			k++
+		}
+	}
+	return DM
+}
+
+// WriteDNDSTable writes pairwise results as tsv
+func WriteDNDSTable(w io.Writer, results []DNDS) error {
+	c := csv.NewWriter(w)
+	c.Comma = '\t'
+	header := []string{"id1", "id2", "codons", "S", "N", "Sd", "Nd", "pS", "pN", "dS", "dN", "dN/dS"}
+	if err := c.Write(header); err != nil {
+		return err
+	}
+	f := func(x float64) string { return strconv.FormatFloat(x, 'f', 4, 64) }
+	for _, R := range results {
+		err := c.Write([]string{R.ID1, R.ID2, strconv.Itoa(R.Codons), f(R.S), f(R.N), f(R.Sd), f(R.Nd), f(R.PS), f(R.PN), f(R.DS), f(R.DN), f(R.Omega)})
+		if err != nil {
+			return err
+		}
+	}
+	c.Flush()
+	return c.Error()
+}
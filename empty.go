@@ -0,0 +1,38 @@
+package main
+
+import "errors"
+
+var (
+	errEmptySequence = errors.New("Record has an empty sequence")
+	errEmptyInput    = errors.New("Input has no fasta records")
+)
+
+// An EmptyPolicy says what the Reader does with an empty sequence or an empty input
+type EmptyPolicy int
+
+const (
+	EmptyAllow EmptyPolicy = iota // the default
+	EmptySkip
+	EmptyError
+)
+
+// WithEmptySequences sets what the Reader does with records that have a header but
+// no sequence: return them with an empty (non-nil) Seq (EmptyAllow, the default),
+// drop them (EmptySkip), or have Read return errEmptySequence (EmptyError). The
+// policy is applied before any transforms, so a record that a transform empties is
+// still returned.
+func WithEmptySequences(policy EmptyPolicy) ReaderOption {
+	return func(r *Reader) {
+		r.emptySeqs = policy
+	}
+}
+
+// WithEmptyInput sets what the Reader does with input that has no records at all:
+// return io.EOF from the first Read (EmptyAllow and EmptySkip, the default), or
+// errEmptyInput (EmptyError). Input whose records are all dropped by a filter or
+// transform isn't empty.
+func WithEmptyInput(policy EmptyPolicy) ReaderOption {
+	return func(r *Reader) {
+		r.emptyInput = policy
+	}
+}
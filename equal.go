@@ -0,0 +1,88 @@
+package main
+
+// Options for Equal
+type EqualOptions struct {
+	IgnoreCase bool // only matters when both records are unencoded
+	IgnoreGaps bool // compare the ungapped sequences
+	NWildcard  bool // N (and ?) in either record matches anything
+}
+
+// Clone returns a deep copy of a record, which shares no memory with the original
+// (apart from the annotation values themselves, which are not copied)
+func (FR *FastaRecord) Clone() FastaRecord {
+	c := *FR
+	c.Detach()
+	return c
+}
+
+// Equal reports whether two records have the same sequence (IDs and descriptions
+// are not compared). An encoded record can be compared with an unencoded one, in
+// which case case is ignored because the encoding doesn't record it.
+func (FR *FastaRecord) Equal(other *FastaRecord, opts EqualOptions) bool {
+
+	plain := !FR.encoded && !other.encoded
+	isGap := func(R *FastaRecord, i int) bool { return R.encodedAt(i) == 244 }
+
+	i, j := 0, 0
+	for {
+		if opts.IgnoreGaps {
+			for i < len(FR.Seq) && isGap(FR, i) {
+				i++
+			}
+			for j < len(other.Seq) && isGap(other, j) {
+				j++
+			}
+		}
+		if i == len(FR.Seq) || j == len(other.Seq) {
+			return i == len(FR.Seq) && j == len(other.Seq)
+		}
+
+		a, b := FR.encodedAt(i), other.encodedAt(j)
+		switch {
+		case opts.NWildcard && (a == 240 || a == 242 || b == 240 || b == 242):
+		case plain && opts.IgnoreCase:
+			if toUpper(FR.Seq[i]) != toUpper(other.Seq[j]) {
+				return false
+			}
+		case plain:
+			if FR.Seq[i] != other.Seq[j] {
+				return false
+			}
+		case a != b:
+			return false
+		}
+		i++
+		j++
+	}
+}
+
+// Compare orders two records by their sequences, returning -1, 0 or 1. Sequences
+// are compared as uppercase, decoded text, so the result is the same whether or not
+// either record is encoded.
+func (FR *FastaRecord) Compare(other *FastaRecord) int {
+	for i := 0; i < len(FR.Seq) && i < len(other.Seq); i++ {
+		a, b := FR.residue(i), other.residue(i)
+		if a < b {
+			return -1
+		} else if a > b {
+			return 1
+		}
+	}
+	switch {
+	case len(FR.Seq) < len(other.Seq):
+		return -1
+	case len(FR.Seq) > len(other.Seq):
+		return 1
+	}
+	return 0
+}
+
+// SeqKey returns the uppercase, decoded sequence as a string, for use as a map key:
+// records with the same sequence have the same key whether or not they are encoded
+func (FR *FastaRecord) SeqKey() string {
+	key := make([]byte, len(FR.Seq))
+	for i := range FR.Seq {
+		key[i] = FR.residue(i)
+	}
+	return string(key)
+}
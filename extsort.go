@@ -0,0 +1,165 @@
+package main
+
+import (
+	"container/heap"
+	"io"
+	"os"
+	"sort"
+)
+
+// What ExternalSort orders records by
+type SortKey int
+
+const (
+	SortByID SortKey = iota
+	SortByLength
+)
+
+// Options for ExternalSort
+type ExternalSortOptions struct {
+	Key        SortKey
+	Descending bool
+	MaxMemory  int64  // roughly how many bytes of records to sort in memory at once (0 for 256 MB)
+	TempDir    string // where to write the sorted runs ("" for the system default)
+}
+
+// less orders two records by the key. Ties are left to the caller, so that the
+// sort is stable.
+func (opts ExternalSortOptions) less(a, b *FastaRecord) bool {
+	if opts.Descending {
+		a, b = b, a
+	}
+	if opts.Key == SortByLength {
+		return len(a.Seq) < len(b.Seq)
+	}
+	return a.ID < b.ID
+}
+
+// ExternalSort sorts the fasta records from r by ID or length onto w, for files too
+// big to sort in memory. Records are read in batches of about opts.MaxMemory bytes,
+// each batch is sorted and written to a temporary file, and then the files are
+// merged. The sort is stable, and the temporary files are removed afterwards.
+func ExternalSort(r io.Reader, w io.Writer, opts ExternalSortOptions) error {
+	if opts.MaxMemory <= 0 {
+		opts.MaxMemory = 256 << 20
+	}
+
+	reader := NewReader(r)
+	runs := make([]*os.File, 0)
+	defer func() {
+		for _, f := range runs {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	batch := make([]FastaRecord, 0)
+	var size int64
+	done := false
+	for !done {
+		FR, err := reader.Read()
+		if err == io.EOF {
+			done = true
+		} else if err != nil {
+			return err
+		} else {
+			batch = append(batch, FR)
+			size += int64(len(FR.Seq) + len(FR.ID) + len(FR.Description))
+			if size < opts.MaxMemory {
+				continue
+			}
+		}
+
+		sort.SliceStable(batch, func(i, j int) bool { return opts.less(&batch[i], &batch[j]) })
+
+		// if everything fitted in one batch there's nothing to merge
+		if done && len(runs) == 0 {
+			writer := NewWriter(w)
+			return writer.WriteAll(batch)
+		}
+		if len(batch) > 0 {
+			f, err := os.CreateTemp(opts.TempDir, "fastaigo-sort-*.fa")
+			if err != nil {
+				return err
+			}
+			runs = append(runs, f)
+			writer := NewWriter(f)
+			writer.LineWidth = 0
+			if err = writer.WriteAll(batch); err != nil {
+				return err
+			}
+		}
+		batch = batch[:0]
+		size = 0
+	}
+
+	return mergeRuns(runs, w, opts)
+}
+
+// the next record from one sorted run
+type runHead struct {
+	FR  FastaRecord
+	run int
+	r   *Reader
+}
+
+type runHeap struct {
+	heads []runHead
+	opts  ExternalSortOptions
+}
+
+func (h *runHeap) Len() int { return len(h.heads) }
+func (h *runHeap) Less(i, j int) bool {
+	a, b := &h.heads[i], &h.heads[j]
+	if h.opts.less(&a.FR, &b.FR) {
+		return true
+	} else if h.opts.less(&b.FR, &a.FR) {
+		return false
+	}
+	// earlier runs hold earlier records, which keeps the sort stable
+	return a.run < b.run
+}
+func (h *runHeap) Swap(i, j int) { h.heads[i], h.heads[j] = h.heads[j], h.heads[i] }
+func (h *runHeap) Push(x any)    { h.heads = append(h.heads, x.(runHead)) }
+func (h *runHeap) Pop() any {
+	x := h.heads[len(h.heads)-1]
+	h.heads = h.heads[:len(h.heads)-1]
+	return x
+}
+
+// mergeRuns merges sorted runs onto w
+func mergeRuns(runs []*os.File, w io.Writer, opts ExternalSortOptions) error {
+	h := &runHeap{heads: make([]runHead, 0, len(runs)), opts: opts}
+	for i, f := range runs {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		r := NewReader(f)
+		FR, err := r.Read()
+		if err == io.EOF {
+			continue
+		} else if err != nil {
+			return err
+		}
+		h.heads = append(h.heads, runHead{FR: FR, run: i, r: r})
+	}
+	heap.Init(h)
+
+	writer := NewWriter(w)
+	for h.Len() > 0 {
+		head := &h.heads[0]
+		if err := writer.Write(head.FR); err != nil {
+			return err
+		}
+		FR, err := head.r.Read()
+		if err == io.EOF {
+			heap.Pop(h)
+			continue
+		} else if err != nil {
+			return err
+		}
+		head.FR = FR
+		heap.Fix(h, 0)
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var errBadlyFormedFai = errors.New("Badly formed fasta index")
+
+// One line of a samtools faidx (.fai) index
+type FaiEntry struct {
+	Name      string
+	Length    int   // the number of bases in the sequence
+	Offset    int64 // the byte offset of the first base in the file
+	LineBases int   // the number of bases on each line
+	LineWidth int   // the number of bytes on each line, including the newline
+}
+
+// ReadFai reads a .fai index
+func ReadFai(r io.Reader) ([]FaiEntry, error) {
+
+	entries := make([]FaiEntry, 0)
+	s := bufio.NewScanner(r)
+
+	for s.Scan() {
+		line := s.Text()
+		if len(line) == 0 {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 5 {
+			return []FaiEntry{}, errBadlyFormedFai
+		}
+		var (
+			E   FaiEntry
+			err error
+		)
+		E.Name = fields[0]
+		if E.Length, err = strconv.Atoi(fields[1]); err != nil {
+			return []FaiEntry{}, errBadlyFormedFai
+		}
+		if E.Offset, err = strconv.ParseInt(fields[2], 10, 64); err != nil {
+			return []FaiEntry{}, errBadlyFormedFai
+		}
+		if E.LineBases, err = strconv.Atoi(fields[3]); err != nil {
+			return []FaiEntry{}, errBadlyFormedFai
+		}
+		if E.LineWidth, err = strconv.Atoi(fields[4]); err != nil {
+			return []FaiEntry{}, errBadlyFormedFai
+		}
+		entries = append(entries, E)
+	}
+	if err := s.Err(); err != nil {
+		return []FaiEntry{}, err
+	}
+
+	return entries, nil
+}
+
+// sizeHintsFromFile fills in opts.Records and opts.SeqLength from f's .fai index
+// if there is one next to it, or opts.FileSize from its size if not. Anything that
+// is already set is left alone, and files that can't be inspected are ignored.
+func (opts *LoadOptions) sizeHintsFromFile(f *os.File) {
+
+	if opts.Records > 0 {
+		return
+	}
+
+	if fai, err := os.Open(f.Name() + ".fai"); err == nil {
+		entries, err := ReadFai(fai)
+		fai.Close()
+		if err == nil && len(entries) > 0 {
+			opts.Records = len(entries)
+			// only pre-size the sequences of an alignment, since one long
+			// chromosome would otherwise make every contig buffer huge
+			if opts.SeqLength == 0 && sameLengths(entries) {
+				opts.SeqLength = entries[0].Length
+			}
+			return
+		}
+	}
+
+	if opts.FileSize == 0 {
+		if info, err := f.Stat(); err == nil && info.Mode().IsRegular() {
+			opts.FileSize = info.Size()
+		}
+	}
+}
+
+// estimateRecords guesses how many records a file of fileSize bytes holds, from the
+// first record, assuming the rest are about the same size
+func estimateRecords(fileSize int64, first FastaRecord) int {
+	// the header, plus the sequence with a newline every DefaultLineWidth bases
+	size := int64(len(first.Description)+2) + int64(len(first.Seq)) + int64(len(first.Seq)/DefaultLineWidth+1)
+	return int(fileSize/size) + 1
+}
+
+func sameLengths(entries []FaiEntry) bool {
+	for _, E := range entries {
+		if E.Length != entries[0].Length {
+			return false
+		}
+	}
+	return true
+}
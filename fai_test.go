@@ -0,0 +1,26 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadSequencesRaggedFileSize(t *testing.T) {
+	in := ">a\nA\n>b\n" + strings.Repeat("ACGT", 250000) + "\n"
+	records, err := LoadSequences(strings.NewReader(in), LoadOptions{FileSize: int64(len(in))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || cap(records) > 16 {
+		t.Errorf("got len %d cap %d", len(records), cap(records))
+	}
+
+	in = ">a\nACGT\n>b\nACGT\n>c\nACGT\n"
+	records, err = LoadSequences(strings.NewReader(in), LoadOptions{EqualWidth: true, FileSize: int64(len(in))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || cap(records) < 3 {
+		t.Errorf("got len %d cap %d", len(records), cap(records))
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+)
+
+var (
+	errBadlyFormedFastq = errors.New("Badly formed Fastq")
+	errBadQuality       = errors.New("Quality score is below phred 0")
+)
+
+// A struct for one Fastq record. Qual holds the quality string as it appears in
+// the file (phred+33 ASCII), and is the same length as Seq.
+type FastqRecord struct {
+	ID          string
+	Description string
+	Seq         []byte
+	Qual        []byte
+	Idx         int
+}
+
+// ToFasta drops the qualities from a fastq record
+func (FQ FastqRecord) ToFasta() FastaRecord {
+	return FastaRecord{ID: FQ.ID, Description: FQ.Description, Seq: FQ.Seq, Idx: FQ.Idx}
+}
+
+// A FastqReader reads records from a fastq file with four lines per record
+type FastqReader struct {
+	r   *bufio.Reader
+	idx int
+}
+
+func NewFastqReader(f io.Reader) *FastqReader {
+	return &FastqReader{r: bufio.NewReader(&newlineReader{r: f})}
+}
+
+// readLine returns the next line without its newline. A last line without a newline
+// is returned with a nil error; io.EOF means there was nothing left.
+func (r *FastqReader) readLine() (string, error) {
+	line, err := r.r.ReadString('\n')
+	if err == io.EOF && len(line) > 0 {
+		err = nil
+	}
+	return strings.TrimSuffix(line, "\n"), err
+}
+
+// Read returns the next record, or io.EOF at the end of the file
+func (r *FastqReader) Read() (FastqRecord, error) {
+	header, err := r.readLine()
+	// skip blank lines between records (and at the end of the file)
+	for err == nil && header == "" {
+		header, err = r.readLine()
+	}
+	if err != nil {
+		return FastqRecord{}, err
+	}
+	if header[0] != '@' {
+		return FastqRecord{}, errBadlyFormedFastq
+	}
+
+	var lines [3]string
+	for i := range lines {
+		if lines[i], err = r.readLine(); err == io.EOF {
+			return FastqRecord{}, errBadlyFormedFastq
+		} else if err != nil {
+			return FastqRecord{}, err
+		}
+	}
+	seq, plus, qual := lines[0], lines[1], lines[2]
+	if len(plus) == 0 || plus[0] != '+' || len(qual) != len(seq) {
+		return FastqRecord{}, errBadlyFormedFastq
+	}
+
+	FQ := FastqRecord{Description: header[1:], Seq: []byte(seq), Qual: []byte(qual), Idx: r.idx}
+	if fields := strings.Fields(FQ.Description); len(fields) > 0 {
+		FQ.ID = fields[0]
+	}
+	r.idx++
+	return FQ, nil
+}
+
+// Options for converting fastq to fasta. Qualities are phred scores (0 upwards),
+// not the ASCII characters.
+type FastqConversion struct {
+	MaskBelow int // bases with a lower quality than this become N (0 for no masking)
+	TrimBelow int // bases at either end with a lower quality than this are removed (0 for no trimming)
+}
+
+// ToFastaMasked converts a fastq record to fasta, trimming and then masking it by
+// quality as set in FC. The sequence is a new slice if anything is masked.
+func (FQ FastqRecord) ToFastaMasked(FC FastqConversion) (FastaRecord, error) {
+	for _, q := range FQ.Qual {
+		if q < 33 {
+			return FastaRecord{}, errBadQuality
+		}
+	}
+	phred := func(i int) int { return int(FQ.Qual[i]) - 33 }
+
+	start, end := 0, len(FQ.Seq)
+	for start < end && phred(start) < FC.TrimBelow {
+		start++
+	}
+	for end > start && phred(end-1) < FC.TrimBelow {
+		end--
+	}
+
+	FR := FQ.ToFasta()
+	FR.Seq = FQ.Seq[start:end]
+	copied := false
+	for i := start; i < end; i++ {
+		if phred(i) >= FC.MaskBelow {
+			continue
+		}
+		if !copied {
+			FR.Seq = append([]byte{}, FR.Seq...)
+			copied = true
+		}
+		FR.Seq[i-start] = 'N'
+	}
+	return FR, nil
+}
+
+// ConvertFastq reads fastq from r and writes it to w as fasta, trimmed and masked
+// by quality as set in FC
+func ConvertFastq(r io.Reader, w io.Writer, FC FastqConversion) error {
+	reader := NewFastqReader(r)
+	writer := NewWriter(w)
+	for {
+		FQ, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		FR, err := FQ.ToFastaMasked(FC)
+		if err != nil {
+			return err
+		}
+		if err = writer.Write(FR); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,61 @@
+package main
+
+// Thresholds for dropping incomplete or low-quality records. Start from
+// DefaultQualityFilter, which lets everything through, and tighten the ones needed.
+type QualityFilter struct {
+	MaxNFraction   float64 // the largest fraction of a record that may be N (or ?)
+	MaxGapFraction float64 // the largest fraction that may be gaps
+	MaxAmbiguous   int     // the most IUPAC ambiguity codes (other than N) allowed, or -1 for no limit
+	MinLength      int     // the fewest ungapped positions allowed
+}
+
+// DefaultQualityFilter removes nothing
+var DefaultQualityFilter = QualityFilter{MaxNFraction: 1, MaxGapFraction: 1, MaxAmbiguous: -1}
+
+// Pass reports whether a record (encoded or not) is within every threshold
+func (QF QualityFilter) Pass(FR FastaRecord) bool {
+	return QF.PassSequence(FR.Sequence())
+}
+
+// PassSequence reports whether any Sequence is within every threshold
+func (QF QualityFilter) PassSequence(s Sequence) bool {
+	bc := countSequenceBases(s)
+	L := s.Len()
+	if L-bc.Gap < QF.MinLength {
+		return false
+	}
+	if QF.MaxAmbiguous >= 0 && bc.Ambiguous > QF.MaxAmbiguous {
+		return false
+	}
+	if L > 0 && (float64(bc.N)/float64(L) > QF.MaxNFraction || float64(bc.Gap)/float64(L) > QF.MaxGapFraction) {
+		return false
+	}
+	return true
+}
+
+// FilterRecords splits records into those that pass the filter and those that don't,
+// keeping their order
+func FilterRecords(records []FastaRecord, QF QualityFilter) (kept, removed []FastaRecord) {
+	kept = make([]FastaRecord, 0, len(records))
+	removed = make([]FastaRecord, 0)
+	for _, FR := range records {
+		if QF.Pass(FR) {
+			kept = append(kept, FR)
+		} else {
+			removed = append(removed, FR)
+		}
+	}
+	return kept, removed
+}
+
+// WithQualityFilter makes the Reader drop every record that doesn't pass the filter,
+// as it streams through the file. It runs as a transform, so its position relative
+// to any other transforms matters.
+func WithQualityFilter(QF QualityFilter) ReaderOption {
+	return WithTransform(func(FR *FastaRecord) error {
+		if !QF.Pass(*FR) {
+			return ErrSkipRecord
+		}
+		return nil
+	})
+}
@@ -0,0 +1,78 @@
+package main
+
+import "fmt"
+
+// The line width used when formatting sequences with String
+const DefaultLineWidth = 60
+
+// header returns the text of a record's header line, without the '>'
+func (FR *FastaRecord) header() string {
+	if FR.idSep != nil {
+		return FR.ID + *FR.idSep + FR.Description
+	}
+	if FR.Description != "" {
+		return FR.Description
+	}
+	return FR.ID
+}
+
+// addToHeader appends a space and text to the record's header line, whether or not
+// its Description includes the ID
+func (FR *FastaRecord) addToHeader(text string) {
+	switch {
+	case FR.idSep == nil:
+		FR.Description = FR.header() + " " + text
+	case FR.Description == "":
+		if *FR.idSep == "" {
+			sep := " "
+			FR.idSep = &sep
+		}
+		FR.Description = text
+	default:
+		FR.Description += " " + text
+	}
+}
+
+// appendFasta appends a record in fasta format to buf, with the given header line,
+// wrapping the sequence every width characters (or not at all if width < 1), and
+// decoding it if it is encoded
+func appendFasta(buf []byte, header string, FR *FastaRecord, width int) []byte {
+	buf = append(buf, '>')
+	buf = append(buf, header...)
+	buf = append(buf, '\n')
+	if width < 1 {
+		width = len(FR.Seq)
+	}
+	for start := 0; start < len(FR.Seq); start += width {
+		end := start + width
+		if end > len(FR.Seq) {
+			end = len(FR.Seq)
+		}
+		if FR.encoded {
+			for _, b := range FR.Seq[start:end] {
+				buf = append(buf, decodingArray[b])
+			}
+		} else {
+			buf = append(buf, FR.Seq[start:end]...)
+		}
+		buf = append(buf, '\n')
+	}
+	return buf
+}
+
+// String returns the record as fasta text, with the sequence (decoded if necessary)
+// wrapped at DefaultLineWidth characters
+func (FR FastaRecord) String() string {
+	return string(appendFasta(make([]byte, 0, len(FR.Seq)+len(FR.Seq)/DefaultLineWidth+len(FR.Description)+4), FR.header(), &FR, DefaultLineWidth))
+}
+
+// Summary returns a one-line description of a record for logging: its ID, length,
+// GC% (of the unambiguous bases) and the percentage of Ns
+func (FR FastaRecord) Summary() string {
+	bc := countBases(&FR)
+	nPercent := 0.0
+	if len(FR.Seq) > 0 {
+		nPercent = 100 * float64(bc.N) / float64(len(FR.Seq))
+	}
+	return fmt.Sprintf("%s length=%d GC=%.2f%% N=%.2f%%", FR.ID, len(FR.Seq), bc.gcPercent(), nPercent)
+}
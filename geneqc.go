@@ -0,0 +1,115 @@
+package main
+
+import "errors"
+
+// A Gene is a coding region on the reference, in 1-based inclusive reference
+// coordinates (as in a GFF file). Only plus-strand genes are supported.
+type Gene struct {
+	Name  string
+	Start int
+	End   int
+}
+
+var errGeneBounds = errors.New("Gene coordinates are outside the reference")
+
+// GenesFromFeatures converts the GFF/GTF features of one type (usually "CDS") on
+// the plus strand into Genes, named by their Feature.Name()
+func GenesFromFeatures(features []Feature, featureType string) []Gene {
+	genes := make([]Gene, 0)
+	for _, F := range features {
+		if F.Type == featureType && F.Strand != '-' {
+			genes = append(genes, Gene{Name: F.Name(), Start: F.Start, End: F.End})
+		}
+	}
+	return genes
+}
+
+// The QC result for one gene in one record
+type GeneQC struct {
+	RecordID      string
+	Gene          string
+	PrematureStop bool
+	StopCodon     int     // 1-based codon number of the first premature stop, 0 if there isn't one
+	Frameshift    bool    // whether any indel in the gene has a length that isn't a multiple of 3
+	Indels        []Indel // the frame-disrupting indels
+}
+
+// CheckGenes looks for premature stop codons and frame-disrupting indels in every
+// gene of every record in an alignment, relative to the reference record refID. For
+// each record the bases aligned to the gene are translated (with gaps removed), and
+// any stop codon before the final codon is reported. Codons that include ambiguous
+// bases are only counted as stops if every possible resolution is a stop.
+func CheckGenes(records []FastaRecord, refID string, genes []Gene, code GeneticCode) ([]GeneQC, error) {
+
+	refIdx := -1
+	for i := range records {
+		if records[i].ID == refID {
+			refIdx = i
+			break
+		}
+	}
+	if refIdx == -1 {
+		return []GeneQC{}, errRecordNotFound
+	}
+	ref := records[refIdx]
+	CM := NewCoordinateMap(ref)
+
+	for _, g := range genes {
+		if g.Start < 1 || g.End < g.Start || g.End > CM.ReferenceLength() {
+			return []GeneQC{}, errGeneBounds
+		}
+	}
+
+	results := make([]GeneQC, 0, len(genes)*(len(records)-1))
+
+	for i, FR := range records {
+		if i == refIdx {
+			continue
+		}
+
+		indels, err := RecordIndels(ref, FR, CM)
+		if err != nil {
+			return []GeneQC{}, err
+		}
+
+		for _, g := range genes {
+			QC := GeneQC{RecordID: FR.ID, Gene: g.Name, Indels: make([]Indel, 0)}
+
+			// indels that start inside the gene (an insertion after its last base is
+			// outside it)
+			for _, ID := range indels {
+				end := g.End - 1
+				if ID.Type == DiffInsertion {
+					end = g.End - 2
+				}
+				if ID.Length%3 != 0 && ID.RefPos >= g.Start-1 && ID.RefPos <= end {
+					QC.Frameshift = true
+					QC.Indels = append(QC.Indels, ID)
+				}
+			}
+
+			// the record's ungapped bases across the gene
+			first, _ := CM.ToAlignment(g.Start - 1)
+			last, _ := CM.ToAlignment(g.End - 1)
+			cds := make([]byte, 0, last-first+1)
+			for j := first; j <= last; j++ {
+				if e := FR.encodedAt(j); e != 244 {
+					cds = append(cds, e)
+				}
+			}
+
+			protein := code.Translate(FastaRecord{Seq: cds, encoded: true})
+			for j := 0; j < len(protein)-1; j++ {
+				if protein[j] == '*' {
+					QC.PrematureStop = true
+					QC.StopCodon = j + 1
+					break
+				}
+			}
+
+			results = append(results, QC)
+		}
+	}
+
+	return results, nil
+}
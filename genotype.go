@@ -0,0 +1,59 @@
+package main
+
+// Genotype returns, for each record of an alignment (encoded or not), the bases at
+// the given 0-based alignment columns joined into one string, e.g. for typing
+// schemes based on lineage-defining SNPs. Bases are upper case, and ambiguity codes
+// are kept as they are so that GenotypesCompatible can allow for them. Bytes that are
+// not valid nucleotides come out as '?'.
+func Genotype(aln []FastaRecord, positions []int) ([]string, error) {
+
+	if len(aln) == 0 {
+		return []string{}, nil
+	}
+	w := len(aln[0].Seq)
+	for _, p := range positions {
+		if p < 0 || p >= w {
+			return []string{}, errSeqBounds
+		}
+	}
+
+	genotypes := make([]string, len(aln))
+	buf := make([]byte, len(positions))
+
+	for i := range aln {
+		if len(aln[i].Seq) != w {
+			return []string{}, errDifferentWidths
+		}
+		for j, p := range positions {
+			buf[j] = decodingArray[aln[i].encodedAt(p)]
+			if buf[j] == 0 {
+				buf[j] = '?'
+			}
+		}
+		genotypes[i] = string(buf)
+	}
+
+	return genotypes, nil
+}
+
+// GenotypesCompatible reports whether two genotypes from Genotype could be the same,
+// allowing for ambiguity codes (so R is compatible with A and G, and N with any
+// base). Gaps are only compatible with gaps.
+func GenotypesCompatible(a, b string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := 0; i < len(a); i++ {
+		x, y := encodingArray[a[i]], encodingArray[b[i]]
+		if x == 244 || y == 244 {
+			if x != y {
+				return false
+			}
+			continue
+		}
+		if x&y < 16 {
+			return false
+		}
+	}
+	return true
+}
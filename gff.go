@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// One feature line from a GFF3 or GTF file. Start and End are 1-based and
+// inclusive, as they are in the file.
+type Feature struct {
+	SeqID      string
+	Source     string
+	Type       string
+	Start      int
+	End        int
+	Score      string
+	Strand     byte // '+', '-' or '.'
+	Phase      int  // -1 if not given
+	Attributes map[string]string
+}
+
+var (
+	errBadlyFormedGFF = errors.New("Badly formed GFF/GTF line")
+	errMissingSeqID   = errors.New("Feature refers to a sequence that is not in the fasta file")
+	errFeatureBounds  = errors.New("Feature coordinates are outside the sequence")
+)
+
+// ReadGFF reads every feature from a GFF3 or GTF file. Comment lines are skipped,
+// and reading stops at a ##FASTA directive. The attribute column is parsed as
+// key=value pairs (GFF3) or key "value" pairs (GTF), separated by semicolons.
+func ReadGFF(r io.Reader) ([]Feature, error) {
+
+	features := make([]Feature, 0)
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for s.Scan() {
+		line := strings.TrimRight(s.Text(), "\r")
+		if strings.HasPrefix(line, "##FASTA") {
+			break
+		}
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 9 {
+			return []Feature{}, errBadlyFormedGFF
+		}
+
+		F := Feature{
+			SeqID:      fields[0],
+			Source:     fields[1],
+			Type:       fields[2],
+			Score:      fields[5],
+			Phase:      -1,
+			Attributes: parseGFFAttributes(fields[8]),
+		}
+
+		var err error
+		if F.Start, err = strconv.Atoi(fields[3]); err != nil {
+			return []Feature{}, errBadlyFormedGFF
+		}
+		if F.End, err = strconv.Atoi(fields[4]); err != nil {
+			return []Feature{}, errBadlyFormedGFF
+		}
+		if F.Start < 1 || F.End < F.Start || len(fields[6]) != 1 {
+			return []Feature{}, errBadlyFormedGFF
+		}
+		F.Strand = fields[6][0]
+		if fields[7] != "." {
+			if F.Phase, err = strconv.Atoi(fields[7]); err != nil || F.Phase < 0 || F.Phase > 2 {
+				return []Feature{}, errBadlyFormedGFF
+			}
+		}
+
+		features = append(features, F)
+	}
+
+	if err := s.Err(); err != nil {
+		return []Feature{}, err
+	}
+
+	return features, nil
+}
+
+func parseGFFAttributes(column string) map[string]string {
+	attributes := make(map[string]string)
+	for _, pair := range strings.Split(column, ";") {
+		pair = strings.TrimSpace(pair)
+		if len(pair) == 0 {
+			continue
+		}
+		if i := strings.IndexByte(pair, '='); i != -1 {
+			attributes[pair[:i]] = pair[i+1:]
+		} else if i := strings.IndexByte(pair, ' '); i != -1 {
+			attributes[pair[:i]] = strings.Trim(strings.TrimSpace(pair[i+1:]), "\"")
+		}
+	}
+	return attributes
+}
+
+// Name returns the identifier that groups the parts of a feature together: the
+// GFF3 ID, or Parent if there is no ID, or for GTF files the transcript_id
+// (gene_id for genes).
+func (F Feature) Name() string {
+	if id, ok := F.Attributes["ID"]; ok {
+		return id
+	}
+	if parent, ok := F.Attributes["Parent"]; ok {
+		return parent
+	}
+	if F.Type != "gene" {
+		if id, ok := F.Attributes["transcript_id"]; ok {
+			return id
+		}
+	}
+	return F.Attributes["gene_id"]
+}
+
+// ExtractFeatures pulls the sequences of every feature of the given type (e.g.
+// "CDS", "exon" or "gene") out of records. Features with the same Name are joined
+// in coordinate order, reverse complemented if they are on the minus strand, and
+// (for features with a phase) trimmed by the phase of the first part so that the
+// sequence begins on a codon boundary. Each new record is named after the
+// feature. The records may be encoded or not.
+func ExtractFeatures(records []FastaRecord, features []Feature, featureType string) ([]FastaRecord, error) {
+
+	bySeqID := make(map[string]*FastaRecord, len(records))
+	for i := range records {
+		bySeqID[records[i].ID] = &records[i]
+	}
+
+	// group the features by name, keeping the order in which each name first appears
+	groups := make(map[string][]Feature)
+	order := make([]string, 0)
+	for _, F := range features {
+		if F.Type != featureType {
+			continue
+		}
+		name := F.Name()
+		if _, ok := groups[name]; !ok {
+			order = append(order, name)
+		}
+		groups[name] = append(groups[name], F)
+	}
+
+	extracted := make([]FastaRecord, 0, len(order))
+
+	for _, name := range order {
+		parts := groups[name]
+		sort.Slice(parts, func(i, j int) bool { return parts[i].Start < parts[j].Start })
+
+		source, ok := bySeqID[parts[0].SeqID]
+		if !ok {
+			return []FastaRecord{}, errMissingSeqID
+		}
+
+		seq := make([]byte, 0)
+		for _, F := range parts {
+			if F.SeqID != parts[0].SeqID {
+				return []FastaRecord{}, errMissingSeqID
+			}
+			if F.End > len(source.Seq) {
+				return []FastaRecord{}, errFeatureBounds
+			}
+			seq = append(seq, source.Seq[F.Start-1:F.End]...)
+		}
+
+		// the part at the 5' end of the feature
+		first := parts[0]
+		if first.Strand == '-' {
+			reverseComplementBytes(seq, source.encoded)
+			first = parts[len(parts)-1]
+		}
+		if first.Phase > 0 && first.Phase <= len(seq) {
+			seq = seq[first.Phase:]
+		}
+
+		description := name + " " + first.SeqID + ":" + strconv.Itoa(parts[0].Start) + "-" +
+			strconv.Itoa(parts[len(parts)-1].End) + "(" + string(first.Strand) + ")"
+
+		extracted = append(extracted, FastaRecord{ID: name, Description: description, Seq: seq, encoded: source.encoded})
+	}
+
+	return extracted, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import "errors"
+
+var errInvalidEncoding = errors.New("Sequence contains a byte that isn't a valid encoded nucleotide")
+
+// The package's encoding is the EP encoding used by gofasta
+// (github.com/cov-ert/gofasta/pkg/encoding), so encoded sequences can be passed
+// between the two packages unchanged. The top four bits are A, G, C and T; bit 8
+// marks an unambiguous base, and bits 2 and 1 mark gaps and '?'.
+const (
+	EncA       byte = 136
+	EncG       byte = 72
+	EncC       byte = 40
+	EncT       byte = 24
+	EncR       byte = 192
+	EncM       byte = 160
+	EncW       byte = 144
+	EncS       byte = 96
+	EncK       byte = 80
+	EncY       byte = 48
+	EncV       byte = 224
+	EncH       byte = 176
+	EncD       byte = 208
+	EncB       byte = 112
+	EncN       byte = 240
+	EncGap     byte = 244
+	EncMissing byte = 242
+)
+
+// Compile-time checks that each ambiguity code is the union of the bases it stands
+// for, without the unambiguous bit: the index is out of range (a compile error) if not
+var (
+	_ = [1]struct{}{}[EncR^(EncA|EncG)&^8]
+	_ = [1]struct{}{}[EncM^(EncA|EncC)&^8]
+	_ = [1]struct{}{}[EncW^(EncA|EncT)&^8]
+	_ = [1]struct{}{}[EncS^(EncG|EncC)&^8]
+	_ = [1]struct{}{}[EncK^(EncG|EncT)&^8]
+	_ = [1]struct{}{}[EncY^(EncC|EncT)&^8]
+	_ = [1]struct{}{}[EncV^(EncA|EncG|EncC)&^8]
+	_ = [1]struct{}{}[EncH^(EncA|EncC|EncT)&^8]
+	_ = [1]struct{}{}[EncD^(EncA|EncG|EncT)&^8]
+	_ = [1]struct{}{}[EncB^(EncG|EncC|EncT)&^8]
+	_ = [1]struct{}{}[EncN^(EncA|EncG|EncC|EncT)&^8]
+)
+
+// the table that both MakeEncodingArray and MakeDecodingArray are built from
+var encodingTable = [...]struct {
+	nuc  byte
+	code byte
+}{
+	{'A', EncA}, {'G', EncG}, {'C', EncC}, {'T', EncT},
+	{'R', EncR}, {'M', EncM}, {'W', EncW}, {'S', EncS}, {'K', EncK}, {'Y', EncY},
+	{'V', EncV}, {'H', EncH}, {'D', EncD}, {'B', EncB}, {'N', EncN},
+	{'-', EncGap}, {'?', EncMissing},
+}
+
+// GofastaSeq returns the record's sequence in gofasta's encoding, for the Seq of a
+// gofasta EncodedFastaRecord. An encoded record's Seq is returned as it is (not
+// copied); an unencoded one is encoded into a new slice.
+func (FR *FastaRecord) GofastaSeq() ([]byte, error) {
+	if FR.encoded {
+		return FR.Seq, nil
+	}
+	seq := make([]byte, len(FR.Seq))
+	for i, nuc := range FR.Seq {
+		if encodingArray[nuc] == 0 {
+			return []byte{}, errInvalidNucleotide
+		}
+		seq[i] = encodingArray[nuc]
+	}
+	return seq, nil
+}
+
+// FromGofasta returns an encoded record from the fields of a gofasta
+// EncodedFastaRecord, checking that every byte of seq is a valid code. The record
+// takes over seq rather than copying it.
+func FromGofasta(id, description string, seq []byte, idx int) (FastaRecord, error) {
+	for _, e := range seq {
+		if decodingArray[e] == 0 {
+			return FastaRecord{}, errInvalidEncoding
+		}
+	}
+	return FastaRecord{ID: id, Description: description, Seq: seq, Idx: idx, encoded: true}, nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+)
+
+// Decompress returns a reader for the decompressed contents of f if it is gzipped,
+// or for f as it is if not. A gzipped file may have several members, as bgzip files
+// and files made by concatenating .gz files do, and they are all read, one after
+// another, as a single stream.
+func Decompress(f io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(f)
+	magic, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(magic) < 2 || magic[0] != 0x1f || magic[1] != 0x8b {
+		return br, nil
+	}
+	gz, err := gzip.NewReader(br)
+	if err != nil {
+		return nil, err
+	}
+	// the default, but every member must be read for concatenated files to work
+	gz.Multistream(true)
+	return gz, nil
+}
+
+// OpenFasta opens a fasta file, which may be gzipped (see Decompress), and returns
+// a Reader for it along with a function that closes the file
+func OpenFasta(path string, opts ...ReaderOption) (*Reader, func() error, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := Decompress(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return NewReader(r, opts...), f.Close, nil
+}
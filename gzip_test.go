@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// the empty block that bgzip writes at the end of every file
+var bgzfEOF = []byte{
+	0x1f, 0x8b, 0x08, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0x06, 0x00, 0x42, 0x43,
+	0x02, 0x00, 0x1b, 0x00, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+}
+
+// gzipMember compresses data as one gzip member, with a BGZF extra field giving the
+// member's size if bgzf is true
+func gzipMember(t *testing.T, data string, bgzf bool) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if bgzf {
+		gz.Extra = []byte{'B', 'C', 2, 0, 0, 0}
+	}
+	if _, err := gz.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	member := buf.Bytes()
+	if bgzf {
+		// BSIZE, the total size of the member minus one, follows the 10 byte header,
+		// XLEN and the subfield's ID and length
+		binary.LittleEndian.PutUint16(member[16:18], uint16(len(member)-1))
+	}
+	return member
+}
+
+// multiMemberFasta returns gzipped fasta made of two plain gzip members and a bgzip
+// member followed by bgzip's EOF block
+func multiMemberFasta(t *testing.T) []byte {
+	data := make([]byte, 0)
+	data = append(data, gzipMember(t, ">a\nACGT\n>b\n", false)...)
+	data = append(data, gzipMember(t, "CCCC\n", false)...)
+	data = append(data, gzipMember(t, ">c\nGGGG\n", true)...)
+	data = append(data, bgzfEOF...)
+	return data
+}
+
+func readIDs(t *testing.T, r RecordReader) []string {
+	ids := make([]string, 0)
+	for {
+		FR, err := r.Read()
+		if err == io.EOF {
+			return ids
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, FR.ID+":"+string(FR.Seq))
+	}
+}
+
+func TestOpenFastaMultiMember(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.fa.gz")
+	if err := os.WriteFile(path, multiMemberFasta(t), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r, closer, err := OpenFasta(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer()
+	got := readIDs(t, r)
+	want := []string{"a:ACGT", "b:CCCC", "c:GGGG"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestNewAutoReaderMultiMember(t *testing.T) {
+	r, format, err := NewAutoReader(bytes.NewReader(multiMemberFasta(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != FormatFasta {
+		t.Errorf("got format %v", format)
+	}
+	got := readIDs(t, r)
+	want := []string{"a:ACGT", "b:CCCC", "c:GGGG"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestNewAutoReaderBgzipEOFOnly(t *testing.T) {
+	r, _, err := NewAutoReader(bytes.NewReader(append(gzipMember(t, ">a\nACGT\n", true), bgzfEOF...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readIDs(t, r); len(got) != 1 || got[0] != "a:ACGT" {
+		t.Errorf("got %v", got)
+	}
+}
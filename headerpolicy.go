@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	errInvalidUTF8Header = errors.New("Header is not valid UTF-8")
+	errNonASCIIHeader    = errors.New("Header contains non-ASCII characters")
+)
+
+// A HeaderPolicy says what the Reader does with non-ASCII bytes in headers
+type HeaderPolicy int
+
+const (
+	// HeaderRaw keeps headers exactly as they are in the file. This is the default.
+	HeaderRaw HeaderPolicy = iota
+	// HeaderUTF8 accepts headers that are valid UTF-8, returning errInvalidUTF8Header
+	// for any that aren't, and turns Unicode whitespace (such as no-break spaces) into
+	// ordinary spaces
+	HeaderUTF8
+	// HeaderStripNonASCII turns Unicode whitespace into spaces and drops every other
+	// non-ASCII character, including bytes that aren't valid UTF-8
+	HeaderStripNonASCII
+	// HeaderRequireASCII returns errNonASCIIHeader for any header with a non-ASCII byte
+	HeaderRequireASCII
+	// HeaderTransliterate behaves like HeaderStripNonASCII, except that accented Latin-1
+	// letters are replaced by their closest ASCII spelling (é becomes e, ß becomes ss,
+	// Æ becomes AE) rather than dropped
+	HeaderTransliterate
+)
+
+// latin1ASCII holds the ASCII spelling of each character from U+00C0 to U+00FF.
+// The two that aren't letters (× and ÷) are dropped.
+var latin1ASCII = [64]string{
+	"A", "A", "A", "A", "A", "A", "AE", "C", "E", "E", "E", "E", "I", "I", "I", "I",
+	"D", "N", "O", "O", "O", "O", "O", "", "O", "U", "U", "U", "U", "Y", "TH", "ss",
+	"a", "a", "a", "a", "a", "a", "ae", "c", "e", "e", "e", "e", "i", "i", "i", "i",
+	"d", "n", "o", "o", "o", "o", "o", "", "o", "u", "u", "u", "u", "y", "th", "y",
+}
+
+// WithHeaderPolicy sets what the Reader does with non-ASCII bytes in headers. The
+// header is cleaned up before it is split into the ID and the description, so
+// under HeaderUTF8 and HeaderStripNonASCII any Unicode whitespace separates them
+// just as a space would.
+func WithHeaderPolicy(policy HeaderPolicy) ReaderOption {
+	return func(r *Reader) {
+		r.headerPolicy = policy
+	}
+}
+
+// cleanHeader applies the Reader's HeaderPolicy to a header (without its >). The
+// result may share memory with header.
+func (r *Reader) cleanHeader(header []byte) ([]byte, error) {
+	if r.headerPolicy == HeaderRaw || isASCII(header) {
+		return header, nil
+	}
+
+	switch r.headerPolicy {
+	case HeaderRequireASCII:
+		return nil, errNonASCIIHeader
+	case HeaderUTF8:
+		if !utf8.Valid(header) {
+			return nil, errInvalidUTF8Header
+		}
+	}
+
+	cleaned := make([]byte, 0, len(header))
+	for len(header) > 0 {
+		c, size := utf8.DecodeRune(header)
+		header = header[size:]
+		switch {
+		case c < utf8.RuneSelf:
+			cleaned = append(cleaned, byte(c))
+		case unicode.IsSpace(c):
+			cleaned = append(cleaned, ' ')
+		case r.headerPolicy == HeaderUTF8:
+			cleaned = utf8.AppendRune(cleaned, c)
+		case r.headerPolicy == HeaderTransliterate && c >= 0xC0 && c <= 0xFF:
+			cleaned = append(cleaned, latin1ASCII[c-0xC0]...)
+		}
+	}
+	return cleaned, nil
+}
+
+func isASCII(b []byte) bool {
+	for _, c := range b {
+		if c >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHeaderPolicy(t *testing.T) {
+	in := ">Café_Müller Straße ×2 \xff丁\nACGT\n"
+	tests := []struct {
+		policy HeaderPolicy
+		header string
+		err    error
+	}{
+		{HeaderRaw, "Café_Müller Straße ×2 \xff丁", nil},
+		{HeaderUTF8, "", errInvalidUTF8Header},
+		{HeaderStripNonASCII, "Caf_Mller Strae 2 ", nil},
+		{HeaderRequireASCII, "", errNonASCIIHeader},
+		{HeaderTransliterate, "Cafe_Muller Strasse 2 ", nil},
+	}
+
+	for _, test := range tests {
+		r := NewReader(strings.NewReader(in), WithHeaderPolicy(test.policy))
+		FR, err := r.Read()
+		if err != test.err {
+			t.Errorf("policy %d: got error %v, want %v", test.policy, err, test.err)
+			continue
+		}
+		if err == nil && FR.header() != test.header {
+			t.Errorf("policy %d: got header %q, want %q", test.policy, FR.header(), test.header)
+		}
+	}
+}
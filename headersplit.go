@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// WithIDDelimiter makes the ID end at the first of any of the bytes in delims,
+// instead of at the first whitespace, e.g. "|" for headers like >sp|P69905|HBA_HUMAN.
+// Leading whitespace is still skipped. An empty delims makes the whole header
+// (less any trailing whitespace) the ID.
+func WithIDDelimiter(delims string) ReaderOption {
+	return func(r *Reader) {
+		r.idDelims = &delims
+	}
+}
+
+// WithDescriptionExcludingID makes Description hold only the part of the header
+// after the ID and its delimiter, with surrounding whitespace trimmed, instead of
+// the whole header. The header is put back together when the record is written.
+func WithDescriptionExcludingID() ReaderOption {
+	return func(r *Reader) {
+		r.trimDescription = true
+	}
+}
+
+// splitHeader sets FR's ID and Description from a header (without its >) according
+// to the Reader's options
+func (r *Reader) splitHeader(FR *FastaRecord, header []byte) {
+	trimmed := bytes.TrimLeftFunc(header, unicode.IsSpace)
+	var end int
+	switch {
+	case r.idDelims == nil:
+		// the same whitespace as bytes.Fields
+		end = bytes.IndexFunc(trimmed, unicode.IsSpace)
+	case *r.idDelims == "":
+		end = len(bytes.TrimRightFunc(trimmed, unicode.IsSpace))
+	default:
+		end = bytes.IndexAny(trimmed, *r.idDelims)
+	}
+	if end < 0 {
+		end = len(trimmed)
+	}
+
+	FR.ID = string(trimmed[:end])
+	if !r.trimDescription {
+		FR.Description = string(header)
+		return
+	}
+	// the delimiter (if it isn't whitespace) and any whitespace after it are kept as
+	// idSep, so that header() can put the header back together
+	afterID := trimmed[end:]
+	sepLen := 0
+	if len(afterID) > 0 && r.idDelims != nil {
+		_, sepLen = utf8.DecodeRune(afterID)
+	}
+	rest := bytes.TrimLeftFunc(afterID[sepLen:], unicode.IsSpace)
+	sep := string(afterID[:len(afterID)-len(rest)])
+	FR.Description = string(bytes.TrimRightFunc(rest, unicode.IsSpace))
+	if FR.Description == "" {
+		sep = strings.TrimRightFunc(sep, unicode.IsSpace)
+	}
+	FR.idSep = &sep
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDescriptionExcludingIDRoundTrip(t *testing.T) {
+	tests := []struct {
+		in     string
+		opts   []ReaderOption
+		ID     string
+		desc   string
+		header string
+	}{
+		{">seq1 some desc\nACGT\n", nil, "seq1", "some desc", "seq1 some desc"},
+		{">seq1\tsome  desc \nACGT\n", nil, "seq1", "some  desc", "seq1\tsome  desc"},
+		{">seq1\nACGT\n", nil, "seq1", "", "seq1"},
+		{">sp|P69905|HBA_HUMAN\nACGT\n", []ReaderOption{WithIDDelimiter("|")}, "sp", "P69905|HBA_HUMAN", "sp|P69905|HBA_HUMAN"},
+		{">sp|\nACGT\n", []ReaderOption{WithIDDelimiter("|")}, "sp", "", "sp|"},
+	}
+
+	for _, test := range tests {
+		opts := append([]ReaderOption{WithDescriptionExcludingID()}, test.opts...)
+		r := NewReader(strings.NewReader(test.in), opts...)
+		FR, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if FR.ID != test.ID || FR.Description != test.desc {
+			t.Errorf("%q: got ID %q, description %q", test.in, FR.ID, FR.Description)
+		}
+
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		if err := w.Write(FR); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if want := ">" + test.header + "\nACGT\n"; buf.String() != want {
+			t.Errorf("%q: wrote %q, want %q", test.in, buf.String(), want)
+		}
+		if _, err := r.Read(); err != io.EOF {
+			t.Errorf("%q: expected EOF, got %v", test.in, err)
+		}
+	}
+}
+
+func TestDescriptionExcludingIDNCBIHeader(t *testing.T) {
+	r := NewReader(strings.NewReader(">gi|12345|gb|AB000001.1| some protein\nACGT\n"), WithDescriptionExcludingID())
+	FR, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	NH, err := FR.NCBIHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(NH) != 1 || NH[0].GI() != "12345" {
+		t.Errorf("got %+v", NH)
+	}
+}
+
+func TestDescriptionExcludingIDDerivedRecords(t *testing.T) {
+	r := NewReader(strings.NewReader(">seq1 some desc\nATGAAA\n"), WithDescriptionExcludingID())
+	FR, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proteins, _, err := TranslateAlignment([]FastaRecord{FR}, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := proteins[0].header(); h != "seq1 some desc" {
+		t.Errorf("translated record has header %q", h)
+	}
+
+	path := filepath.Join(t.TempDir(), "store")
+	S, err := CreateSeqStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := S.Put(FR); err != nil {
+		t.Fatal(err)
+	}
+	if err := S.Close(); err != nil {
+		t.Fatal(err)
+	}
+	S, err = OpenSeqStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer S.Close()
+	stored, err := S.Get("seq1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Description != "some desc" || stored.header() != "seq1 some desc" {
+		t.Errorf("stored record has description %q, header %q", stored.Description, stored.header())
+	}
+}
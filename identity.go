@@ -0,0 +1,65 @@
+package main
+
+import (
+	"math"
+	"strconv"
+)
+
+// Identity returns the proportion of sites where FR has the same base as ref, a
+// record of the same width (either may be encoded or not), and the number of sites
+// compared. Only sites where both have an unambiguous base are compared, so Ns,
+// gaps and other ambiguity codes are ignored; the identity is NaN if there are none.
+func Identity(ref, FR FastaRecord) (float64, int, error) {
+	return SequenceIdentity(ref.Sequence(), FR.Sequence())
+}
+
+// SequenceIdentity is Identity for any two Sequences of the same length
+func SequenceIdentity(ref, s Sequence) (float64, int, error) {
+	if ref.Len() != s.Len() {
+		return math.NaN(), 0, errDifferentWidths
+	}
+	compared, differences := sequenceDifferences(viewOf(ref), viewOf(s))
+	if compared == 0 {
+		return math.NaN(), 0, nil
+	}
+	return 1 - float64(differences)/float64(compared), compared, nil
+}
+
+// An IdentityFilter catches records that are too unlike a reference to be the same
+// organism, such as contaminants in a batch of submissions
+type IdentityFilter struct {
+	Reference   FastaRecord
+	MinIdentity float64 // records with a lower Identity to Reference fail, as do records with nothing to compare
+	// If Flag is true failing records are kept but marked, by appending
+	// " low_identity=<identity>" to their description, rather than dropped
+	Flag bool
+}
+
+// Pass reports whether a record passes the filter, and its identity to the reference
+func (IF IdentityFilter) Pass(FR FastaRecord) (bool, float64, error) {
+	identity, _, err := Identity(IF.Reference, FR)
+	if err != nil {
+		return false, identity, err
+	}
+	return identity >= IF.MinIdentity, identity, nil
+}
+
+// WithIdentityFilter makes the Reader drop (or flag) every record that doesn't pass
+// the filter, as it streams through the file. A record of a different width to the
+// reference is an error. It runs as a transform, so its position relative to any
+// other transforms matters.
+func WithIdentityFilter(IF IdentityFilter) ReaderOption {
+	return WithTransform(func(FR *FastaRecord) error {
+		pass, identity, err := IF.Pass(*FR)
+		switch {
+		case err != nil:
+			return err
+		case pass:
+			return nil
+		case !IF.Flag:
+			return ErrSkipRecord
+		}
+		FR.addToHeader("low_identity=" + strconv.FormatFloat(identity, 'f', 4, 64))
+		return nil
+	})
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestIdentityFilterFlagKeepsID(t *testing.T) {
+	ref := FastaRecord{ID: "ref", Seq: []byte("ACGT")}
+	in := ">a\nACGA\n>b desc\nTTTT\n>c\nTTTT\n"
+
+	for _, opts := range [][]ReaderOption{nil, {WithDescriptionExcludingID()}} {
+		opts = append(opts, WithIdentityFilter(IdentityFilter{Reference: ref, MinIdentity: 0.5, Flag: true}))
+		r := NewReader(strings.NewReader(in), opts...)
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		for {
+			FR, err := r.Read()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Write(FR); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		want := ">a\nACGA\n>b desc low_identity=0.2500\nTTTT\n>c low_identity=0.2500\nTTTT\n"
+		if buf.String() != want {
+			t.Errorf("got %q, want %q", buf.String(), want)
+		}
+	}
+
+	FR := FastaRecord{ID: "d", Seq: []byte("TTTT")}
+	FR.addToHeader("low_identity=0.0000")
+	if FR.header() != "d low_identity=0.0000" {
+		t.Errorf("got header %q", FR.header())
+	}
+}
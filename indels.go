@@ -0,0 +1,119 @@
+package main
+
+// An insertion or deletion in one record relative to a reference, in reference
+// coordinates (0-based). For a deletion, RefPos is the first deleted reference
+// base. For an insertion, RefPos is the reference base immediately before the
+// inserted sequence, or -1 if it comes before the start of the reference.
+type Indel struct {
+	RecordID string
+	Type     DiffType // DiffInsertion or DiffDeletion
+	RefPos   int
+	Length   int
+	Seq      string // the inserted bases, or the deleted reference bases
+}
+
+// RecordIndels returns the insertions and deletions in query relative to ref, which
+// must be records of the same alignment. Columns where both have a gap are ignored,
+// so an insertion that is interrupted by a column of gaps is still reported once.
+// CM must have been built from ref.
+func RecordIndels(ref, query FastaRecord, CM *CoordinateMap) ([]Indel, error) {
+
+	if len(ref.Seq) != len(query.Seq) {
+		return []Indel{}, errDifferentWidths
+	}
+
+	indels := make([]Indel, 0)
+
+	// the open indel (if any) and the alignment columns that it spans
+	var current *Indel
+	var start, end int
+
+	closeIndel := func() {
+		if current == nil {
+			return
+		}
+		source := &query
+		if current.Type == DiffDeletion {
+			source = &ref
+		}
+		seq := make([]byte, 0, current.Length)
+		for j := start; j < end; j++ {
+			if e := source.encodedAt(j); e != 244 {
+				seq = append(seq, decodingArray[e])
+			}
+		}
+		current.Seq = string(seq)
+		indels = append(indels, *current)
+		current = nil
+	}
+
+	for i := range ref.Seq {
+		refGap := ref.encodedAt(i) == 244
+		queryGap := query.encodedAt(i) == 244
+
+		var DT DiffType
+		switch {
+		case refGap && queryGap:
+			// neither has sequence here, so any open indel carries on
+			continue
+		case refGap:
+			DT = DiffInsertion
+		case queryGap:
+			DT = DiffDeletion
+		default:
+			closeIndel()
+			continue
+		}
+
+		if current != nil && current.Type != DT {
+			closeIndel()
+		}
+		if current == nil {
+			current = &Indel{RecordID: query.ID, Type: DT}
+			if DT == DiffInsertion {
+				current.RefPos = CM.PrecedingReference(i)
+			} else {
+				current.RefPos, _ = CM.ToReference(i)
+			}
+			start = i
+		}
+		current.Length++
+		end = i + 1
+	}
+	closeIndel()
+
+	return indels, nil
+}
+
+// FindIndels returns the insertions and deletions relative to the record with ID
+// refID, for every other record in an alignment
+func FindIndels(records []FastaRecord, refID string) ([]Indel, error) {
+
+	refIdx := -1
+	for i := range records {
+		if records[i].ID == refID {
+			refIdx = i
+			break
+		}
+	}
+	if refIdx == -1 {
+		return []Indel{}, errRecordNotFound
+	}
+
+	ref := records[refIdx]
+	CM := NewCoordinateMap(ref)
+
+	indels := make([]Indel, 0)
+	for i, FR := range records {
+		if i == refIdx {
+			continue
+		}
+		recordIndels, err := RecordIndels(ref, FR, CM)
+		if err != nil {
+			return []Indel{}, err
+		}
+		indels = append(indels, recordIndels...)
+	}
+
+	return indels, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import "strings"
+
+// These adapters let the Reader and Writer act as the I/O layer for other Go
+// bioinformatics packages without this package depending on them. Sequence types
+// elsewhere are generally a named type whose letters are bytes (biogo's
+// alphabet.Letters is a []alphabet.Letter, where Letter is a byte), so they can be
+// converted with ToLetters and FromLetters, e.g. for biogo:
+//
+//	s := linear.NewSeq(FR.ID, ToLetters[alphabet.Letter](FR), alphabet.DNAgapped)
+//	s.Desc = DescriptionWithoutID(FR)
+//	...
+//	FR := FromNamed(s, s.Seq)
+
+// A Named sequence has a name and a description, like biogo's seq.Sequence
+type Named interface {
+	Name() string
+	Description() string
+}
+
+// ToLetters returns a copy of the record's sequence (decoded if necessary) as a
+// slice of any byte-based letter type
+func ToLetters[L ~byte](FR FastaRecord) []L {
+	letters := make([]L, len(FR.Seq))
+	for i, b := range FR.Seq {
+		if FR.encoded {
+			b = decodingArray[b]
+		}
+		letters[i] = L(b)
+	}
+	return letters
+}
+
+// FromLetters returns an unencoded record holding a copy of letters. description is
+// the text that follows the ID in the header, which may be empty.
+func FromLetters[L ~byte](id, description string, letters []L) FastaRecord {
+	seq := make([]byte, len(letters))
+	for i, l := range letters {
+		seq[i] = byte(l)
+	}
+	FR := FastaRecord{ID: id, Seq: seq}
+	if description != "" {
+		FR.Description = id + " " + description
+	}
+	return FR
+}
+
+// FromNamed returns an unencoded record with the name and description of a Named
+// sequence and a copy of its letters
+func FromNamed[L ~byte](s Named, letters []L) FastaRecord {
+	return FromLetters(s.Name(), s.Description(), letters)
+}
+
+// DescriptionWithoutID returns the part of the record's header after its ID, which is
+// what other packages usually mean by a description
+func DescriptionWithoutID(FR FastaRecord) string {
+	return strings.TrimLeft(strings.TrimPrefix(FR.header(), FR.ID), " \t")
+}
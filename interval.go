@@ -0,0 +1,52 @@
+package main
+
+import "sort"
+
+// A region of a sequence. Start and End are 0-based, half-open (as in BED files).
+type Interval struct {
+	Start int
+	End   int
+}
+
+// mergeIntervals sorts intervals and merges any that overlap or touch
+func mergeIntervals(intervals []Interval) []Interval {
+	if len(intervals) == 0 {
+		return intervals
+	}
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].Start < intervals[j].Start
+	})
+	merged := []Interval{intervals[0]}
+	for _, iv := range intervals[1:] {
+		last := &merged[len(merged)-1]
+		if iv.Start <= last.End {
+			if iv.End > last.End {
+				last.End = iv.End
+			}
+		} else {
+			merged = append(merged, iv)
+		}
+	}
+	return merged
+}
+
+// runIntervals returns the runs of consecutive positions in a record (encoded or
+// not) whose encoded value satisfies in
+func runIntervals(FR *FastaRecord, in func(e byte) bool) []Interval {
+	runs := make([]Interval, 0)
+	start := -1
+	for i := range FR.Seq {
+		if in(FR.encodedAt(i)) {
+			if start == -1 {
+				start = i
+			}
+		} else if start != -1 {
+			runs = append(runs, Interval{Start: start, End: i})
+			start = -1
+		}
+	}
+	if start != -1 {
+		runs = append(runs, Interval{Start: start, End: len(FR.Seq)})
+	}
+	return runs
+}
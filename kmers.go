@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"sort"
+)
+
+var errBadKmerSize = errors.New("k-mer size must be between 1 and 32")
+
+// forEachKmer calls fn on the canonical form (the smaller of it and its reverse
+// complement, two bits per base) of every k-mer in FR, skipping any k-mer that
+// contains something other than A, C, G or T
+func forEachKmer(FR *FastaRecord, k int, fn func(uint64)) {
+
+	mask := uint64(1)<<(2*uint(k)) - 1 // all ones when k is 32
+	shift := 2 * uint(k-1)
+
+	var fwd, rev uint64
+	run := 0 // the number of valid bases in a row
+
+	for i := range FR.Seq {
+		b := baseIndex(FR.encodedAt(i))
+		if b < 0 {
+			run = 0
+			continue
+		}
+		fwd = (fwd<<2 | uint64(b)) & mask
+		rev = rev>>2 | uint64(3-b)<<shift
+		run++
+		if run >= k {
+			if fwd < rev {
+				fn(fwd)
+			} else {
+				fn(rev)
+			}
+		}
+	}
+}
+
+// A KmerSet is a compact set of the canonical k-mers in one reference (a sorted
+// slice of 2-bit packed k-mers), for quickly screening whether query sequences come
+// from it. Because k-mers are canonical, queries match on either strand.
+type KmerSet struct {
+	K     int
+	kmers []uint64
+}
+
+// NewKmerSet builds the set of k-mers in records (e.g. the contigs of one genome),
+// which may be encoded or not
+func NewKmerSet(records []FastaRecord, k int) (*KmerSet, error) {
+
+	if k < 1 || k > 32 {
+		return nil, errBadKmerSize
+	}
+
+	kmers := make([]uint64, 0)
+	for i := range records {
+		forEachKmer(&records[i], k, func(kmer uint64) {
+			kmers = append(kmers, kmer)
+		})
+	}
+
+	sort.Slice(kmers, func(i, j int) bool { return kmers[i] < kmers[j] })
+
+	// deduplicate in place
+	unique := kmers[:0]
+	for i, kmer := range kmers {
+		if i == 0 || kmer != kmers[i-1] {
+			unique = append(unique, kmer)
+		}
+	}
+
+	return &KmerSet{K: k, kmers: unique}, nil
+}
+
+// Len returns the number of distinct k-mers in the set
+func (KS *KmerSet) Len() int {
+	return len(KS.kmers)
+}
+
+func (KS *KmerSet) has(kmer uint64) bool {
+	i := sort.Search(len(KS.kmers), func(i int) bool { return KS.kmers[i] >= kmer })
+	return i < len(KS.kmers) && KS.kmers[i] == kmer
+}
+
+// Contains returns the fraction of the k-mers in query that are in the set, from 0
+// (nothing in common) to 1 (every k-mer is present, as for a contig that came from
+// this reference). A query with no valid k-mers scores 0.
+func (KS *KmerSet) Contains(query FastaRecord) float64 {
+	total, found := 0, 0
+	forEachKmer(&query, KS.K, func(kmer uint64) {
+		total++
+		if KS.has(kmer) {
+			found++
+		}
+	})
+	if total == 0 {
+		return 0
+	}
+	return float64(found) / float64(total)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Summary statistics for the lengths of the records in a file
+type LengthStats struct {
+	Count  int
+	Total  int
+	Min    int
+	Max    int
+	Mean   float64
+	Q1     float64 // the 25th percentile
+	Median float64
+	Q3     float64 // the 75th percentile
+	sorted []int
+}
+
+// A bin of a length histogram, counting the records with Start <= length < End
+type LengthBin struct {
+	Start int
+	End   int
+	Count int
+}
+
+func lengthStats(lengths []int) LengthStats {
+	sort.Ints(lengths)
+	LS := LengthStats{Count: len(lengths), sorted: lengths}
+	if LS.Count == 0 {
+		return LS
+	}
+	for _, l := range lengths {
+		LS.Total += l
+	}
+	LS.Min = lengths[0]
+	LS.Max = lengths[len(lengths)-1]
+	LS.Mean = float64(LS.Total) / float64(LS.Count)
+	LS.Q1 = LS.Percentile(25)
+	LS.Median = LS.Percentile(50)
+	LS.Q3 = LS.Percentile(75)
+	return LS
+}
+
+// CalcLengthStats reads every record from r and returns the length statistics.
+// Only the lengths are kept in memory, not the sequences.
+func CalcLengthStats(r io.Reader) (LengthStats, error) {
+	lengths := make([]int, 0)
+	reader := NewReader(r)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return LengthStats{}, err
+		}
+		lengths = append(lengths, len(record.Seq))
+	}
+	return lengthStats(lengths), nil
+}
+
+// LengthStatsFromRecords returns the length statistics for records that are already in memory
+func LengthStatsFromRecords(records []FastaRecord) LengthStats {
+	lengths := make([]int, len(records))
+	for i := range records {
+		lengths[i] = len(records[i].Seq)
+	}
+	return lengthStats(lengths)
+}
+
+// Percentile returns the pth percentile (0-100) of the lengths, interpolating
+// linearly between the nearest two, or 0 if there are no records
+func (LS LengthStats) Percentile(p float64) float64 {
+	if LS.Count == 0 {
+		return 0
+	}
+	pos := p / 100 * float64(LS.Count-1)
+	lo := int(math.Floor(pos))
+	if lo < 0 {
+		return float64(LS.sorted[0])
+	} else if lo >= LS.Count-1 {
+		return float64(LS.sorted[LS.Count-1])
+	}
+	frac := pos - float64(lo)
+	return float64(LS.sorted[lo]) + frac*float64(LS.sorted[lo+1]-LS.sorted[lo])
+}
+
+// Histogram counts the records in bins of binWidth, from the bin that holds the
+// shortest record to the one that holds the longest (including any empty bins in
+// between). If binWidth < 1 a width giving about 20 bins is used.
+func (LS LengthStats) Histogram(binWidth int) []LengthBin {
+	if LS.Count == 0 {
+		return []LengthBin{}
+	}
+	if binWidth < 1 {
+		binWidth = (LS.Max-LS.Min)/20 + 1
+	}
+	first := LS.Min / binWidth
+	last := LS.Max / binWidth
+	bins := make([]LengthBin, last-first+1)
+	for i := range bins {
+		bins[i].Start = (first + i) * binWidth
+		bins[i].End = bins[i].Start + binWidth
+	}
+	for _, l := range LS.sorted {
+		bins[l/binWidth-first].Count++
+	}
+	return bins
+}
+
+// WriteLengthHistogram writes histogram bins as tsv
+func WriteLengthHistogram(w io.Writer, bins []LengthBin) error {
+	c := csv.NewWriter(w)
+	c.Comma = '\t'
+	if err := c.Write([]string{"start", "end", "count"}); err != nil {
+		return err
+	}
+	for _, LB := range bins {
+		if err := c.Write([]string{strconv.Itoa(LB.Start), strconv.Itoa(LB.End), strconv.Itoa(LB.Count)}); err != nil {
+			return err
+		}
+	}
+	c.Flush()
+	return c.Error()
+}
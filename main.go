@@ -2,9 +2,9 @@ package main
 
 import (
 	"bufio"
-	"bytes"
 	"errors"
 	"io"
+	"os"
 )
 
 // A struct for one Fasta record
@@ -18,7 +18,10 @@ type FastaRecord struct {
 	Count_C     int
 	Score       int64 // this is for e.g., genome completeness
 	Idx         int
+	Circular    bool           // e.g. for plasmids and mitochondrial genomes
+	Annotations map[string]any // arbitrary metadata, e.g. QC flags added by a pipeline
 	encoded     bool
+	idSep       *string // set if Description excludes the ID: the text between them in the header
 }
 
 // Encode a fasta record, panics if the record is already encoded or if there are
@@ -27,14 +30,18 @@ func (FR *FastaRecord) MustEncode() {
 	if FR.encoded {
 		panic("Fasta record is already encoded")
 	}
-	EA := MakeEncodingArray()
-	for i, nuc := range FR.Seq {
-		if EA[nuc] == 0 {
+	encodeBytes(FR.Seq)
+	FR.encoded = true
+}
+
+// encodeBytes encodes seq in place, and panics if there are invalid nucleotides
+func encodeBytes(seq []byte) {
+	for i, nuc := range seq {
+		if encodingArray[nuc] == 0 {
 			panic("invalid nucleotide in file: \"" + string(nuc) + "\"")
 		}
-		FR.Seq[i] = EA[nuc]
+		seq[i] = encodingArray[nuc]
 	}
-	FR.encoded = true
 }
 
 // Decode a fasta record, panics if the record is already decoded
@@ -55,20 +62,147 @@ var (
 )
 
 type Reader struct {
-	r *bufio.Reader
+	r               *bufio.Reader
+	nl              newlineReader
+	transforms      []func(*FastaRecord) error
+	headerFilter    func(id, description string) bool
+	headerPolicy    HeaderPolicy
+	idDelims        *string // nil to split the header at the first whitespace
+	trimDescription bool
+	seqCapacity     int // if > 0, the capacity each sequence buffer starts with
+	encode          bool
+	pooled          bool
+
+	// see WithEmptySequences and WithEmptyInput
+	emptySeqs  EmptyPolicy
+	emptyInput EmptyPolicy
+	parsed     int
+
+	// for recovery mode (see WithRecovery)
+	recovering bool
+	recovered  []RecoveredError
+	returned   int
+
+	// for the block parser (see WithBlockParsing)
+	block  []byte
+	pos    int
+	header []byte
+}
+
+// A ReaderOption configures a Reader
+type ReaderOption func(*Reader)
+
+// NewReader returns a Reader that reads from f. Lines can end in \n, \r\n or \r.
+func NewReader(f io.Reader, opts ...ReaderOption) *Reader {
+	r := &Reader{nl: newlineReader{r: f}}
+	r.r = bufio.NewReader(&r.nl)
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Reset discards any buffered data and makes the Reader read from f instead,
+// keeping its options and its buffers, so one Reader can be reused across many
+// files without reallocating. Recovered errors (see WithRecovery) are cleared.
+func (r *Reader) Reset(f io.Reader) {
+	r.nl = newlineReader{r: f}
+	r.r.Reset(&r.nl)
+	r.parsed, r.returned, r.recovered = 0, 0, nil
+	if r.block != nil {
+		r.block = r.block[:0]
+		r.pos = 0
+	}
 }
 
-func NewReader(f io.Reader) *Reader {
-	return &Reader{r: bufio.NewReader(f)}
+// ErrSkipRecord can be returned by a transform to drop the current record. Read
+// then moves on to the next one.
+var ErrSkipRecord = errors.New("Skip this record")
+
+// WithTransform adds a function that is run on every record as it is read, before
+// Read returns it (e.g. to uppercase, trim, score or filter records without a
+// second pass). Transforms run in the order they were added. If one returns
+// ErrSkipRecord the record is dropped, and any other error is returned by Read.
+func WithTransform(transform func(*FastaRecord) error) ReaderOption {
+	return func(r *Reader) {
+		r.transforms = append(r.transforms, transform)
+	}
+}
+
+// WithHeaderFilter makes the Reader skip every record for which keep returns false.
+// The filter sees only the header, so the sequence lines of unwanted records are
+// discarded without being buffered, which makes pulling a few records out of a huge
+// file much faster.
+func WithHeaderFilter(keep func(id, description string) bool) ReaderOption {
+	return func(r *Reader) {
+		r.headerFilter = keep
+	}
+}
+
+// WithEncoding makes the Reader encode each sequence line as it is read, rather
+// than the whole sequence being encoded afterwards with MustEncode, which saves a
+// pass over every sequence. Like MustEncode, Read panics on invalid nucleotides.
+// Transforms see the encoded record.
+func WithEncoding() ReaderOption {
+	return func(r *Reader) {
+		r.encode = true
+	}
 }
 
 // Read reads one fasta record from the underlying reader. The final record is returned with error = nil,
 // and the next call to Read() returns an empty FastaRecord struct and error = io.EOF.
 func (r *Reader) Read() (FastaRecord, error) {
 
+records:
+	for {
+		var (
+			FR  FastaRecord
+			err error
+		)
+		if r.block != nil {
+			FR, err = r.readBlock()
+		} else {
+			FR, err = r.read()
+		}
+		if err != nil && r.recovering && recoverable(err) {
+			r.recovered = append(r.recovered, RecoveredError{Record: r.returned, Err: err})
+			if err = r.resync(); err != nil {
+				return FastaRecord{}, err
+			}
+			continue
+		}
+		if err == io.EOF && r.emptyInput == EmptyError && r.parsed == 0 {
+			return FastaRecord{}, errEmptyInput
+		} else if err != nil {
+			return FR, err
+		}
+		r.parsed++
+		if len(FR.Seq) == 0 {
+			switch r.emptySeqs {
+			case EmptySkip:
+				continue records
+			case EmptyError:
+				return FastaRecord{}, errEmptySequence
+			}
+		}
+		for _, transform := range r.transforms {
+			err = transform(&FR)
+			if err == ErrSkipRecord {
+				continue records
+			} else if err != nil {
+				return FastaRecord{}, err
+			}
+		}
+		r.returned++
+		return FR, nil
+	}
+}
+
+// read parses the next record from the underlying reader
+func (r *Reader) read() (FastaRecord, error) {
+
 	var (
 		buffer, line, peek []byte
-		fields             [][]byte
 		err                error
 		FR                 FastaRecord
 	)
@@ -87,7 +221,11 @@ func (r *Reader) Read() (FastaRecord, error) {
 			// For simple uses, a Scanner may be more convenient."
 			line, err = r.r.ReadBytes('\n')
 
-			// return even if err == io.EOF, because the file should never end on a fasta header line
+			// a header at the very end of the file, without a newline, is a record with
+			// an empty sequence (see WithEmptySequences)
+			if err == io.EOF && len(line) > 0 {
+				err = nil
+			}
 			if err != nil {
 				return FastaRecord{}, err
 
@@ -96,40 +234,48 @@ func (r *Reader) Read() (FastaRecord, error) {
 				return FastaRecord{}, errBadlyFormedFasta
 			}
 
-			drop := 0
-			// Strip unix or dos newline characters from the header before setting the description.
+			// Strip the newline from the header before setting the description (dos and old Mac
+			// line endings have already been converted by newlineReader).
 			if line[len(line)-1] == '\n' {
-				drop = 1
-				if len(line) > 1 && line[len(line)-2] == '\r' {
-					drop = 2
-				}
-				line = line[:len(line)-drop]
+				line = line[:len(line)-1]
 			}
 
-			// split the header on whitespace
-			fields = bytes.Fields(line[1:])
-			// fasta ID
-			FR.ID = string(fields[0])
-			// fasta description
-			FR.Description = string(line[1:])
+			// deal with any non-ASCII characters
+			line, err = r.cleanHeader(line[1:])
+			if err != nil {
+				return FastaRecord{}, err
+			}
+
+			// split the header into the fasta ID and description
+			r.splitHeader(&FR, line)
 
 			// we are no longer on a header line
 			first = false
 
+			// discard unwanted records and start again on the next header
+			if r.headerFilter != nil && !r.headerFilter(FR.ID, FR.Description) {
+				if err = r.skipSequence(); err != nil {
+					return FastaRecord{}, err
+				}
+				FR = FastaRecord{}
+				first = true
+			}
+
 		} else {
 
 			// peek at the first next byte of the underlying reader, in order
 			// to see if we've reached the end of this record (or the file)
 			peek, err = r.r.Peek(1)
 
+			// other errors are returned along with an empty fasta record
+			if err != nil && err != io.EOF {
+				return FastaRecord{}, err
+			}
+
 			// both these cases are fine if first = false, so we can exit the loop and return the fasta record
 			if err == io.EOF || peek[0] == '>' {
 				err = nil
 				break
-
-				// other errors are returned along with an empty fasta record
-			} else if err != nil {
-				return FastaRecord{}, err
 			}
 
 			// If we've got this far, this should be a sequence line.
@@ -140,29 +286,90 @@ func (r *Reader) Read() (FastaRecord, error) {
 				return FastaRecord{}, err
 			}
 
-			drop := 0
-			// Strip unix or dos newline characters from the sequence before appending it.
+			// Strip the newline from the sequence before appending it (dos and old Mac
+			// line endings have already been converted by newlineReader).
 			if line[len(line)-1] == '\n' {
-				drop = 1
-				if len(line) > 1 && line[len(line)-2] == '\r' {
-					drop = 2
-				}
-				line = line[:len(line)-drop]
+				line = line[:len(line)-1]
 			}
 
+			if r.encode {
+				if err = r.encodeLine(line); err != nil {
+					return FastaRecord{}, err
+				}
+			}
+			if buffer == nil {
+				buffer = r.newSeqBuffer()
+			}
 			buffer = append(buffer, line...)
 		}
 	}
 
+	if buffer == nil {
+		buffer = []byte{}
+	}
 	FR.Seq = buffer
+	FR.encoded = r.encode
 
 	return FR, err
 }
 
+// skipSequence discards sequence lines up to the next header or the end of the file
+func (r *Reader) skipSequence() error {
+	for {
+		peek, err := r.r.Peek(1)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		} else if peek[0] == '>' {
+			return nil
+		}
+
+		// ReadSlice doesn't copy the line. ErrBufferFull just means that the line is
+		// longer than the buffer, so we keep reading until we reach the end of it.
+		for {
+			_, err = r.r.ReadSlice('\n')
+			if err != bufio.ErrBufferFull {
+				break
+			}
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+	}
+}
+
+// LoadAlignment reads every record from r and encodes it, returning errDifferentWidths
+// if the records are not all the same length
 func LoadAlignment(r io.Reader) ([]FastaRecord, error) {
+	return LoadSequences(r, LoadOptions{EqualWidth: true, Encode: true})
+}
+
+// Options for LoadSequences
+type LoadOptions struct {
+	EqualWidth bool // return errDifferentWidths if the records are not all the same length
+	Encode     bool // encode every record (panics on invalid nucleotides)
+
+	// Size hints, used only to pre-allocate memory. If r is an *os.File they are
+	// filled in from its .fai index (if there is one) or its size.
+	Records   int   // the number of records
+	SeqLength int   // the length of the sequences
+	FileSize  int64 // the size of the input in bytes, to estimate Records from the first record if EqualWidth is set
+}
+
+// LoadSequences reads every record from r. Unlike LoadAlignment, the records may be
+// different lengths unless opts.EqualWidth is set, and they are only encoded if
+// opts.Encode is set, so the same loader serves aligned and unaligned input.
+func LoadSequences(r io.Reader, opts LoadOptions) ([]FastaRecord, error) {
 
-	records := make([]FastaRecord, 0)
+	if f, ok := r.(*os.File); ok {
+		opts.sizeHintsFromFile(f)
+	}
+
+	records := make([]FastaRecord, 0, opts.Records)
 	reader := NewReader(r)
+	reader.seqCapacity = opts.SeqLength
+	reader.encode = opts.Encode
 
 	first := true
 	var w int
@@ -174,15 +381,24 @@ func LoadAlignment(r io.Reader) ([]FastaRecord, error) {
 		} else if err != nil {
 			return []FastaRecord{}, err
 		}
-		record.MustEncode()
 
 		if first {
 			w = len(record.Seq)
 			first = false
-		} else if len(record.Seq) != w {
+			// every other record should be the same length as this one
+			if opts.EqualWidth {
+				reader.seqCapacity = w
+			}
+			// the first record says little about the rest unless they're all the same
+			// length, and a short one would reserve far too much
+			if opts.EqualWidth && opts.Records == 0 && opts.FileSize > 0 {
+				records = make([]FastaRecord, 0, estimateRecords(opts.FileSize, record))
+			}
+		} else if opts.EqualWidth && len(record.Seq) != w {
 			return []FastaRecord{}, errDifferentWidths
 		}
 
+		record.Idx = len(records)
 		records = append(records, record)
 	}
 
@@ -190,8 +406,16 @@ func LoadAlignment(r io.Reader) ([]FastaRecord, error) {
 }
 
 func StreamAlignment(r io.Reader, chnl chan FastaRecord, chnlerr chan error, cdone chan bool) {
+	StreamAlignmentLimited(r, chnl, chnlerr, cdone, nil)
+}
 
-	reader := NewReader(r)
+// StreamAlignmentLimited is StreamAlignment with a bound on the number of records in
+// flight: a slot is acquired from limiter before each record is sent, and the consumer
+// must call limiter.Release() once it has finished with the record. A nil limiter
+// means no bound.
+func StreamAlignmentLimited(r io.Reader, chnl chan FastaRecord, chnlerr chan error, cdone chan bool, limiter *InFlightLimiter) {
+
+	reader := NewReader(r, WithEncoding())
 	counter := 0
 
 	first := true
@@ -205,7 +429,6 @@ func StreamAlignment(r io.Reader, chnl chan FastaRecord, chnlerr chan error, cdo
 			chnlerr <- err
 			return
 		}
-		record.MustEncode()
 
 		if first {
 			w = len(record.Seq)
@@ -218,71 +441,35 @@ func StreamAlignment(r io.Reader, chnl chan FastaRecord, chnlerr chan error, cdo
 		record.Idx = counter
 		counter++
 
+		limiter.Acquire()
 		chnl <- record
 	}
 
 	cdone <- true
 }
 
+// Package-level copies of the encoding and decoding arrays, for functions that
+// look up bytes one at a time and shouldn't rebuild the arrays on every call
+var (
+	encodingArray = MakeEncodingArray()
+	decodingArray = MakeDecodingArray()
+)
+
+// MakeEncodingArray returns the table for encoding fasta text, in either case
 func MakeEncodingArray() [256]byte {
 	var byteArray [256]byte
-
-	byteArray['A'] = 136
-	byteArray['a'] = 136
-	byteArray['G'] = 72
-	byteArray['g'] = 72
-	byteArray['C'] = 40
-	byteArray['c'] = 40
-	byteArray['T'] = 24
-	byteArray['t'] = 24
-	byteArray['R'] = 192
-	byteArray['r'] = 192
-	byteArray['M'] = 160
-	byteArray['m'] = 160
-	byteArray['W'] = 144
-	byteArray['w'] = 144
-	byteArray['S'] = 96
-	byteArray['s'] = 96
-	byteArray['K'] = 80
-	byteArray['k'] = 80
-	byteArray['Y'] = 48
-	byteArray['y'] = 48
-	byteArray['V'] = 224
-	byteArray['v'] = 224
-	byteArray['H'] = 176
-	byteArray['h'] = 176
-	byteArray['D'] = 208
-	byteArray['d'] = 208
-	byteArray['B'] = 112
-	byteArray['b'] = 112
-	byteArray['N'] = 240
-	byteArray['n'] = 240
-	byteArray['-'] = 244
-	byteArray['?'] = 242
-
+	for _, E := range encodingTable {
+		byteArray[E.nuc] = E.code
+		byteArray[toLower(E.nuc)] = E.code
+	}
 	return byteArray
 }
 
+// MakeDecodingArray returns the table for decoding to (uppercase) fasta text
 func MakeDecodingArray() [256]byte {
 	var byteArray [256]byte
-
-	byteArray[136] = 'A'
-	byteArray[72] = 'G'
-	byteArray[40] = 'C'
-	byteArray[24] = 'T'
-	byteArray[192] = 'R'
-	byteArray[160] = 'M'
-	byteArray[144] = 'W'
-	byteArray[96] = 'S'
-	byteArray[80] = 'K'
-	byteArray[48] = 'Y'
-	byteArray[224] = 'V'
-	byteArray[176] = 'H'
-	byteArray[208] = 'D'
-	byteArray[112] = 'B'
-	byteArray[240] = 'N'
-	byteArray[244] = '-'
-	byteArray[242] = '?'
-
+	for _, E := range encodingTable {
+		byteArray[E.code] = E.nuc
+	}
 	return byteArray
 }
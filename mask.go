@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maskRange masks the positions [start, end) of a record in place. The caller is
+// responsible for not soft masking an encoded record.
+func (FR *FastaRecord) maskRange(start, end int, mode MaskMode) {
+	for i := start; i < end; i++ {
+		switch {
+		case mode == HardMask && FR.encoded:
+			FR.Seq[i] = 240
+		case mode == HardMask:
+			FR.Seq[i] = 'N'
+		case FR.Seq[i] >= 'A' && FR.Seq[i] <= 'Z':
+			FR.Seq[i] += 'a' - 'A'
+		}
+	}
+}
+
+var (
+	errBadlyFormedBED = errors.New("Badly formed BED line")
+	errMaskBounds     = errors.New("Mask interval is outside the sequence")
+)
+
+// ReadBED reads the first three columns of a BED file, returning the intervals
+// grouped by the first column. Header, track and comment lines are skipped.
+func ReadBED(r io.Reader) (map[string][]Interval, error) {
+
+	intervals := make(map[string][]Interval)
+	s := bufio.NewScanner(r)
+
+	for s.Scan() {
+		line := strings.TrimRight(s.Text(), "\r")
+		if len(line) == 0 || line[0] == '#' || strings.HasPrefix(line, "track") || strings.HasPrefix(line, "browser") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 3 {
+			return nil, errBadlyFormedBED
+		}
+		start, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, errBadlyFormedBED
+		}
+		end, err := strconv.Atoi(fields[2])
+		if err != nil || start < 0 || end < start {
+			return nil, errBadlyFormedBED
+		}
+		intervals[fields[0]] = append(intervals[fields[0]], Interval{Start: start, End: end})
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return intervals, nil
+}
+
+// MaskRecords masks each record in an alignment, in place, using its own intervals
+// from masks (keyed by record ID). Records without an entry in masks are left alone,
+// but an entry for an ID that isn't in the alignment is an error.
+//
+// If CM is nil, the intervals are alignment columns. Otherwise they are positions in
+// the reference that CM was built from (e.g. from a per-sample coverage BED), and
+// masking covers every alignment column from the first to the last position of each
+// interval, including any columns inserted relative to the reference.
+func MaskRecords(records []FastaRecord, masks map[string][]Interval, CM *CoordinateMap, mode MaskMode) error {
+
+	byID := make(map[string]*FastaRecord, len(records))
+	for i := range records {
+		byID[records[i].ID] = &records[i]
+	}
+
+	for id, intervals := range masks {
+		FR, ok := byID[id]
+		if !ok {
+			return errRecordNotFound
+		}
+		if mode == SoftMask && FR.encoded {
+			return errSoftMaskEncoded
+		}
+		for _, iv := range intervals {
+			if iv.Start >= iv.End {
+				continue
+			}
+			start, end := iv.Start, iv.End
+			if CM != nil {
+				var okStart, okEnd bool
+				start, okStart = CM.ToAlignment(iv.Start)
+				end, okEnd = CM.ToAlignment(iv.End - 1)
+				if !okStart || !okEnd {
+					return errMaskBounds
+				}
+				end++
+			}
+			if start < 0 || end > len(FR.Seq) {
+				return errMaskBounds
+			}
+			FR.maskRange(start, end, mode)
+		}
+	}
+
+	return nil
+}
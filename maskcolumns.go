@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// What MaskColumns does to the masked columns
+type ColumnMaskMode int
+
+const (
+	ColumnsToN    ColumnMaskMode = iota // replace every base in the column with N
+	ColumnsToGap                        // replace with gaps
+	ColumnsRemove                       // remove the columns
+)
+
+var errBadlyFormedVCF = errors.New("Badly formed VCF line")
+
+// MaskColumns masks whole columns of an alignment (encoded or not), e.g. known
+// problematic or homoplasic sites. If CM is nil the intervals are alignment columns;
+// otherwise they are positions in the reference CM was built from, and each covers
+// every column from its first to its last position. It returns masked copies of the
+// records and a ColumnMap back to the original alignment (which only differs from
+// the identity for ColumnsRemove).
+func MaskColumns(aln []FastaRecord, intervals []Interval, CM *CoordinateMap, mode ColumnMaskMode) ([]FastaRecord, *ColumnMap, error) {
+
+	w := 0
+	if len(aln) > 0 {
+		w = len(aln[0].Seq)
+	}
+	for _, FR := range aln {
+		if len(FR.Seq) != w {
+			return []FastaRecord{}, nil, errDifferentWidths
+		}
+	}
+
+	masked := make([]bool, w)
+	for _, iv := range intervals {
+		if iv.Start >= iv.End {
+			continue
+		}
+		start, end := iv.Start, iv.End
+		if CM != nil {
+			var okStart, okEnd bool
+			start, okStart = CM.ToAlignment(iv.Start)
+			end, okEnd = CM.ToAlignment(iv.End - 1)
+			if !okStart || !okEnd {
+				return []FastaRecord{}, nil, errMaskBounds
+			}
+			end++
+		}
+		if start < 0 || end > w {
+			return []FastaRecord{}, nil, errMaskBounds
+		}
+		for j := start; j < end; j++ {
+			masked[j] = true
+		}
+	}
+
+	kept := make([]int, 0, w)
+	for j := 0; j < w; j++ {
+		if mode != ColumnsRemove || !masked[j] {
+			kept = append(kept, j)
+		}
+	}
+
+	if mode == ColumnsRemove {
+		return selectColumns(aln, kept), NewColumnMap(kept, w), nil
+	}
+
+	out := make([]FastaRecord, len(aln))
+	for i := range aln {
+		out[i] = aln[i].Clone()
+		var fill byte
+		switch {
+		case mode == ColumnsToN && out[i].encoded:
+			fill = 240
+		case mode == ColumnsToN:
+			fill = 'N'
+		case out[i].encoded:
+			fill = 244
+		default:
+			fill = '-'
+		}
+		for j, m := range masked {
+			if m {
+				out[i].Seq[j] = fill
+			}
+		}
+	}
+
+	return out, NewColumnMap(kept, w), nil
+}
+
+// PositionIntervals turns a list of 0-based positions into merged intervals, for
+// MaskColumns
+func PositionIntervals(positions []int) []Interval {
+	intervals := make([]Interval, len(positions))
+	for i, p := range positions {
+		intervals[i] = Interval{Start: p, End: p + 1}
+	}
+	return mergeIntervals(intervals)
+}
+
+// ReadProblematicSites reads the positions from a VCF of sites to mask, such as the
+// SARS-CoV-2 problematic sites list, returning them 0-based. If maskOnly is true,
+// only sites whose FILTER column is "mask" are returned (leaving out, e.g., those
+// marked "caution").
+func ReadProblematicSites(r io.Reader, maskOnly bool) ([]int, error) {
+
+	positions := make([]int, 0)
+	s := bufio.NewScanner(r)
+
+	for s.Scan() {
+		line := strings.TrimRight(s.Text(), "\r")
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 7 {
+			return []int{}, errBadlyFormedVCF
+		}
+		pos, err := strconv.Atoi(fields[1])
+		if err != nil || pos < 1 {
+			return []int{}, errBadlyFormedVCF
+		}
+		if maskOnly && fields[6] != "mask" {
+			continue
+		}
+		positions = append(positions, pos-1)
+	}
+	if err := s.Err(); err != nil {
+		return []int{}, err
+	}
+
+	return positions, nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// the kinds of missing data, by encoded value
+func isN(e byte) bool         { return e == 240 || e == 242 }
+func isGap(e byte) bool       { return e == 244 }
+func isAmbiguous(e byte) bool { return e != 0 && e&8 == 0 && !isN(e) && !isGap(e) }
+
+// The missing data in one record. Intervals are 0-based, half-open alignment columns.
+type MissingData struct {
+	RecordID           string
+	Length             int
+	N                  int // includes '?'
+	Gaps               int
+	Ambiguous          int // IUPAC codes other than N
+	NIntervals         []Interval
+	GapIntervals       []Interval
+	AmbiguousIntervals []Interval
+}
+
+// Missing data across a whole alignment
+type MissingDataSummary struct {
+	Records           int
+	Width             int
+	MeanNFraction     float64 // the mean over records of the fraction of each that is N
+	MeanGapFraction   float64
+	MaxNFraction      float64
+	MaxNRecord        string // the record with the highest N fraction
+	TotalAmbiguous    int
+	ColumnsAnyMissing int // columns where at least one record has an N or a gap
+	ColumnsAllMissing int // columns where every record has an N or a gap
+}
+
+// MissingDataReport lists the Ns, gaps and ambiguity codes in every record of an
+// alignment (encoded or not), with a summary of the whole alignment, as a QC check
+// before phylogenetics
+func MissingDataReport(aln []FastaRecord) ([]MissingData, MissingDataSummary, error) {
+
+	summary := MissingDataSummary{Records: len(aln)}
+	if len(aln) == 0 {
+		return []MissingData{}, summary, nil
+	}
+	summary.Width = len(aln[0].Seq)
+
+	report := make([]MissingData, len(aln))
+	missing := make([]int, summary.Width) // the number of records with an N or gap in each column
+
+	for i := range aln {
+		FR := &aln[i]
+		if len(FR.Seq) != summary.Width {
+			return []MissingData{}, MissingDataSummary{}, errDifferentWidths
+		}
+
+		bc := countBases(FR)
+		MD := MissingData{
+			RecordID:           FR.ID,
+			Length:             len(FR.Seq),
+			N:                  bc.N,
+			Gaps:               bc.Gap,
+			Ambiguous:          bc.Ambiguous,
+			NIntervals:         runIntervals(FR, isN),
+			GapIntervals:       runIntervals(FR, isGap),
+			AmbiguousIntervals: runIntervals(FR, isAmbiguous),
+		}
+		report[i] = MD
+
+		for j := range FR.Seq {
+			if e := FR.encodedAt(j); isN(e) || isGap(e) {
+				missing[j]++
+			}
+		}
+
+		if MD.Length > 0 {
+			nFraction := float64(MD.N) / float64(MD.Length)
+			summary.MeanNFraction += nFraction
+			summary.MeanGapFraction += float64(MD.Gaps) / float64(MD.Length)
+			if i == 0 || nFraction > summary.MaxNFraction {
+				summary.MaxNFraction = nFraction
+				summary.MaxNRecord = FR.ID
+			}
+		}
+		summary.TotalAmbiguous += MD.Ambiguous
+	}
+
+	summary.MeanNFraction /= float64(len(aln))
+	summary.MeanGapFraction /= float64(len(aln))
+
+	for _, m := range missing {
+		if m > 0 {
+			summary.ColumnsAnyMissing++
+		}
+		if m == len(aln) {
+			summary.ColumnsAllMissing++
+		}
+	}
+
+	return report, summary, nil
+}
+
+// A run of Ns or of ambiguity codes in one record
+type AmbiguityRun struct {
+	RecordID string
+	Start    int // 0-based, half-open
+	End      int
+	Kind     string // "N" or "ambiguous"
+}
+
+// AmbiguityRuns finds the runs of Ns (or ?) and the runs of other ambiguity codes in
+// each record (encoded or not). If CM is nil the runs are in alignment columns.
+// Otherwise they are converted to positions in the reference that CM was built
+// from, spanning the first to the last reference position covered; runs that lie
+// wholly in insertions relative to the reference are left out. Either way the
+// result can be written with WriteAmbiguityBED and read back with ReadBED, e.g. to
+// pass to MaskRecords.
+func AmbiguityRuns(records []FastaRecord, CM *CoordinateMap) []AmbiguityRun {
+
+	runs := make([]AmbiguityRun, 0)
+
+	add := func(id, kind string, intervals []Interval) {
+		for _, iv := range intervals {
+			start, end := iv.Start, iv.End
+			if CM != nil {
+				start, end = -1, -1
+				for j := iv.Start; j < iv.End; j++ {
+					if p, ok := CM.ToReference(j); ok {
+						if start == -1 {
+							start = p
+						}
+						end = p + 1
+					}
+				}
+				if start == -1 {
+					continue
+				}
+			}
+			runs = append(runs, AmbiguityRun{RecordID: id, Start: start, End: end, Kind: kind})
+		}
+	}
+
+	for i := range records {
+		FR := &records[i]
+		add(FR.ID, "N", runIntervals(FR, isN))
+		add(FR.ID, "ambiguous", runIntervals(FR, isAmbiguous))
+	}
+
+	return runs
+}
+
+// WriteAmbiguityBED writes runs as BED lines, with the record ID as the chromosome
+// and the kind of run as the name
+func WriteAmbiguityBED(w io.Writer, runs []AmbiguityRun) error {
+	bw := bufio.NewWriter(w)
+	for _, AR := range runs {
+		if _, err := fmt.Fprintf(bw, "%s\t%d\t%d\t%s\n", AR.RecordID, AR.Start, AR.End, AR.Kind); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
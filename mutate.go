@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"sort"
+)
+
+var (
+	errBadMutation          = errors.New("Invalid mutation")
+	errOverlappingMutations = errors.New("Mutations affect the same reference base")
+)
+
+// A change to a reference sequence, in 0-based reference coordinates. As for an
+// Indel, an insertion's RefPos is the reference base immediately before the
+// inserted sequence (or -1 to insert before the start).
+type Mutation struct {
+	Type   DiffType // DiffSubstitution, DiffInsertion or DiffDeletion
+	RefPos int
+	Seq    string // the new bases for a substitution (one or more), or the inserted bases
+	Length int    // the number of bases deleted
+}
+
+// ApplyMutations applies mutations to a copy of a record (encoded or not), e.g. to
+// build a synthetic variant genome. The mutations can be given in any order, but no
+// two substitutions or deletions may touch the same base. It returns the mutated
+// record and a CoordinateMap in which the original record is the reference and the
+// mutated sequence takes the place of the alignment, for converting positions
+// between the two (deleted bases have no position in the mutated sequence).
+func ApplyMutations(FR FastaRecord, mutations []Mutation) (FastaRecord, *CoordinateMap, error) {
+
+	L := len(FR.Seq)
+
+	// what happens at each reference base, and what is inserted after it (shifted by
+	// one so that index 0 is before the start)
+	replacement := make([]byte, L)
+	deleted := make([]bool, L)
+	inserted := make([][]byte, L+1)
+	touched := make([]bool, L)
+
+	encode := func(s string) ([]byte, error) {
+		b := []byte(s)
+		for i := range b {
+			if encodingArray[b[i]] == 0 {
+				return nil, errBadMutation
+			}
+			if FR.encoded {
+				b[i] = encodingArray[b[i]]
+			}
+		}
+		return b, nil
+	}
+
+	sorted := make([]Mutation, len(mutations))
+	copy(sorted, mutations)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].RefPos < sorted[j].RefPos })
+
+	for _, M := range sorted {
+		switch M.Type {
+		case DiffSubstitution:
+			bases, err := encode(M.Seq)
+			if err != nil || len(bases) == 0 {
+				return FastaRecord{}, nil, errBadMutation
+			}
+			if M.RefPos < 0 || M.RefPos+len(bases) > L {
+				return FastaRecord{}, nil, errSeqBounds
+			}
+			for i, b := range bases {
+				if touched[M.RefPos+i] {
+					return FastaRecord{}, nil, errOverlappingMutations
+				}
+				touched[M.RefPos+i] = true
+				replacement[M.RefPos+i] = b
+			}
+		case DiffDeletion:
+			if M.Length < 1 {
+				return FastaRecord{}, nil, errBadMutation
+			}
+			if M.RefPos < 0 || M.RefPos+M.Length > L {
+				return FastaRecord{}, nil, errSeqBounds
+			}
+			for i := M.RefPos; i < M.RefPos+M.Length; i++ {
+				if touched[i] {
+					return FastaRecord{}, nil, errOverlappingMutations
+				}
+				touched[i] = true
+				deleted[i] = true
+			}
+		case DiffInsertion:
+			bases, err := encode(M.Seq)
+			if err != nil || len(bases) == 0 {
+				return FastaRecord{}, nil, errBadMutation
+			}
+			if M.RefPos < -1 || M.RefPos >= L {
+				return FastaRecord{}, nil, errSeqBounds
+			}
+			inserted[M.RefPos+1] = append(inserted[M.RefPos+1], bases...)
+		default:
+			return FastaRecord{}, nil, errBadMutation
+		}
+	}
+
+	seq := make([]byte, 0, L)
+	CM := &CoordinateMap{refToAln: make([]int, L), alnToRef: make([]int, 0, L)}
+
+	addInserted := func(bases []byte) {
+		for _, b := range bases {
+			seq = append(seq, b)
+			CM.alnToRef = append(CM.alnToRef, -1)
+		}
+	}
+
+	addInserted(inserted[0])
+	for i := 0; i < L; i++ {
+		if deleted[i] {
+			CM.refToAln[i] = -1
+		} else {
+			b := FR.Seq[i]
+			if replacement[i] != 0 {
+				b = replacement[i]
+			}
+			CM.refToAln[i] = len(seq)
+			seq = append(seq, b)
+			CM.alnToRef = append(CM.alnToRef, i)
+		}
+		addInserted(inserted[i+1])
+	}
+
+	mutated := FR
+	mutated.Seq = seq
+	return mutated, CM, nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var errBadlyFormedDefline = errors.New("Badly formed NCBI defline")
+
+// ncbiFieldCounts is the number of |-separated fields that follow each NCBI
+// database tag in a seq-id, e.g. gi|12345 or ref|NC_000913.3|locus
+var ncbiFieldCounts = map[string]int{
+	"bbm": 1, "bbs": 1, "gi": 1, "gim": 1, "lcl": 1,
+	"dbj": 2, "emb": 2, "gb": 2, "gnl": 2, "gpp": 2, "nat": 2, "pdb": 2, "pir": 2,
+	"prf": 2, "ref": 2, "sp": 2, "tpd": 2, "tpe": 2, "tpg": 2, "tr": 2,
+	"pat": 3, "pgp": 3,
+}
+
+// databases whose seq-ids hold an identifier that isn't an accession
+var ncbiNonAccession = map[string]bool{"bbm": true, "bbs": true, "gi": true, "gim": true, "gnl": true, "lcl": true, "pat": true, "pgp": true}
+
+// An NCBISeqID is one identifier from an NCBI defline, such as gi|12345 or
+// ref|NC_000913.3|. Fields has one entry per field for the database, with "" for
+// empty or missing ones. DB is "" for an identifier without a database tag.
+type NCBISeqID struct {
+	DB     string
+	Fields []string
+}
+
+// An NCBIDefline is one of the deflines in a header: the seq-ids, and the title
+// that follows them
+type NCBIDefline struct {
+	IDs   []NCBISeqID
+	Title string
+}
+
+// NCBIHeader holds the deflines of a header. There is more than one when the
+// header joins the deflines of identical sequences with ^A (\x01) characters, as
+// in the nr database.
+type NCBIHeader []NCBIDefline
+
+// ParseNCBIHeader parses a header (without its >) that follows the NCBI defline
+// conventions
+func ParseNCBIHeader(header string) (NCBIHeader, error) {
+	parts := strings.Split(header, "\x01")
+	NH := make(NCBIHeader, 0, len(parts))
+	for _, part := range parts {
+		part = strings.TrimLeftFunc(part, unicode.IsSpace)
+		idText, title := part, ""
+		if i := strings.IndexFunc(part, unicode.IsSpace); i >= 0 {
+			idText, title = part[:i], strings.TrimSpace(part[i:])
+		}
+		ids, err := parseNCBISeqIDs(idText)
+		if err != nil {
+			return NCBIHeader{}, err
+		}
+		NH = append(NH, NCBIDefline{IDs: ids, Title: title})
+	}
+	return NH, nil
+}
+
+// parseNCBISeqIDs parses the |-separated seq-ids at the start of a defline
+func parseNCBISeqIDs(s string) ([]NCBISeqID, error) {
+	if s == "" {
+		return []NCBISeqID{}, errBadlyFormedDefline
+	}
+	if !strings.Contains(s, "|") {
+		return []NCBISeqID{{Fields: []string{s}}}, nil
+	}
+
+	tokens := strings.Split(s, "|")
+	ids := make([]NCBISeqID, 0)
+	for i := 0; i < len(tokens); {
+		db := tokens[i]
+		n, ok := ncbiFieldCounts[db]
+		if !ok {
+			// a trailing | leaves an empty token at the end
+			if db == "" && i == len(tokens)-1 {
+				break
+			}
+			return []NCBISeqID{}, errBadlyFormedDefline
+		}
+		i++
+		fields := make([]string, n)
+		for j := 0; j < n && i < len(tokens); j++ {
+			fields[j] = tokens[i]
+			i++
+		}
+		ids = append(ids, NCBISeqID{DB: db, Fields: fields})
+	}
+	return ids, nil
+}
+
+// NCBIHeader parses the record's header according to the NCBI defline conventions
+func (FR *FastaRecord) NCBIHeader() (NCBIHeader, error) {
+	return ParseNCBIHeader(FR.header())
+}
+
+// Lookup returns the first seq-id from database db
+func (ND NCBIDefline) Lookup(db string) (NCBISeqID, bool) {
+	for _, id := range ND.IDs {
+		if id.DB == db {
+			return id, true
+		}
+	}
+	return NCBISeqID{}, false
+}
+
+// GI returns the defline's gi number, or "" if it doesn't have one
+func (ND NCBIDefline) GI() string {
+	if id, ok := ND.Lookup("gi"); ok {
+		return id.Fields[0]
+	}
+	return ""
+}
+
+// Accession returns the first accession (with its version, if it has one) among
+// the defline's seq-ids, or "" if there isn't one. An identifier without a
+// database tag is taken to be an accession.
+func (ND NCBIDefline) Accession() string {
+	for _, id := range ND.IDs {
+		if !ncbiNonAccession[id.DB] && id.Fields[0] != "" {
+			return id.Fields[0]
+		}
+	}
+	return ""
+}
+
+// SplitAccession splits an accession.version such as NC_000913.3 into the
+// accession and the version. ok is false if there is no version.
+func SplitAccession(s string) (accession string, version int, ok bool) {
+	i := strings.LastIndexByte(s, '.')
+	if i < 0 {
+		return s, 0, false
+	}
+	version, err := strconv.Atoi(s[i+1:])
+	if err != nil || version < 0 {
+		return s, 0, false
+	}
+	return s[:i], version, true
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var errBadlyFormedNewick = errors.New("Badly formed Newick tree")
+
+// ReadNewickTips reads the first tree from a Newick file and returns its tip labels
+// in the order they appear. Branch lengths, internal node labels and [comments] are
+// skipped. Quoted labels are unquoted, but underscores in unquoted labels are kept
+// as they are (rather than becoming spaces), since they usually match sequence IDs.
+func ReadNewickTips(r io.Reader) ([]string, error) {
+
+	br := bufio.NewReader(r)
+	tips := make([]string, 0)
+	depth := 0
+	// whether the next label is a tip (one after a ')' labels an internal node)
+	tipNext := true
+	started := false
+
+	for {
+		c, err := br.ReadByte()
+		if err == io.EOF {
+			return []string{}, errBadlyFormedNewick
+		} else if err != nil {
+			return []string{}, err
+		}
+
+		switch c {
+		case ' ', '\t', '\n', '\r':
+		case '(':
+			depth++
+			started = true
+			tipNext = true
+		case ',':
+			if depth == 0 {
+				return []string{}, errBadlyFormedNewick
+			}
+			tipNext = true
+		case ')':
+			depth--
+			if depth < 0 {
+				return []string{}, errBadlyFormedNewick
+			}
+			tipNext = false
+		case ';':
+			if depth != 0 {
+				return []string{}, errBadlyFormedNewick
+			}
+			if !started {
+				return []string{}, errBadlyFormedNewick
+			}
+			return tips, nil
+		case '[':
+			if _, err = br.ReadString(']'); err != nil {
+				return []string{}, errBadlyFormedNewick
+			}
+		case ':':
+			// a branch length, which ends at the next structural character
+			if err = skipNewickLength(br); err != nil {
+				return []string{}, err
+			}
+		default:
+			br.UnreadByte()
+			label, err := readNewickLabel(br)
+			if err != nil {
+				return []string{}, err
+			}
+			if tipNext {
+				tips = append(tips, label)
+			}
+			started = true
+			tipNext = false
+		}
+	}
+}
+
+func skipNewickLength(br *bufio.Reader) error {
+	for {
+		c, err := br.ReadByte()
+		if err != nil {
+			return errBadlyFormedNewick
+		}
+		if strings.IndexByte(",);[", c) >= 0 {
+			return br.UnreadByte()
+		}
+	}
+}
+
+// readNewickLabel reads a quoted or unquoted label
+func readNewickLabel(br *bufio.Reader) (string, error) {
+
+	c, _ := br.ReadByte()
+	var sb strings.Builder
+
+	if c == '\'' {
+		for {
+			c, err := br.ReadByte()
+			if err != nil {
+				return "", errBadlyFormedNewick
+			}
+			if c == '\'' {
+				// '' is an escaped quote
+				if next, err := br.Peek(1); err == nil && next[0] == '\'' {
+					br.ReadByte()
+				} else {
+					return sb.String(), nil
+				}
+			}
+			sb.WriteByte(c)
+		}
+	}
+
+	sb.WriteByte(c)
+	for {
+		c, err := br.ReadByte()
+		if err != nil {
+			return "", errBadlyFormedNewick
+		}
+		if strings.IndexByte("(),:;[ \t\n\r", c) >= 0 {
+			return sb.String(), br.UnreadByte()
+		}
+		sb.WriteByte(c)
+	}
+}
+
+// A TipMismatchError lists the labels that stop an alignment being put in a tree's
+// tip order
+type TipMismatchError struct {
+	NotInAlignment []string // tips with no record
+	NotInTree      []string // records with no tip
+	Duplicated     []string // labels that appear more than once in the tree or the alignment
+}
+
+func (E *TipMismatchError) Error() string {
+	parts := make([]string, 0, 3)
+	if len(E.NotInAlignment) > 0 {
+		parts = append(parts, fmt.Sprintf("%d tip(s) not in the alignment (e.g. %q)", len(E.NotInAlignment), E.NotInAlignment[0]))
+	}
+	if len(E.NotInTree) > 0 {
+		parts = append(parts, fmt.Sprintf("%d record(s) not in the tree (e.g. %q)", len(E.NotInTree), E.NotInTree[0]))
+	}
+	if len(E.Duplicated) > 0 {
+		parts = append(parts, fmt.Sprintf("%d duplicated label(s) (e.g. %q)", len(E.Duplicated), E.Duplicated[0]))
+	}
+	return "Tree tips do not match the alignment: " + strings.Join(parts, "; ")
+}
+
+// OrderByTips returns the records reordered to match the tree's tip order, e.g. from
+// ReadNewickTips. Every tip must match exactly one record ID and vice versa;
+// otherwise the error is a *TipMismatchError listing the problem labels.
+func OrderByTips(records []FastaRecord, tips []string) ([]FastaRecord, error) {
+
+	E := &TipMismatchError{}
+
+	byID := make(map[string]int, len(records))
+	for i, FR := range records {
+		if _, ok := byID[FR.ID]; ok {
+			E.Duplicated = append(E.Duplicated, FR.ID)
+		}
+		byID[FR.ID] = i
+	}
+
+	ordered := make([]FastaRecord, 0, len(tips))
+	seen := make(map[string]bool, len(tips))
+	for _, tip := range tips {
+		if seen[tip] {
+			E.Duplicated = append(E.Duplicated, tip)
+			continue
+		}
+		seen[tip] = true
+		i, ok := byID[tip]
+		if !ok {
+			E.NotInAlignment = append(E.NotInAlignment, tip)
+			continue
+		}
+		ordered = append(ordered, records[i])
+	}
+	for _, FR := range records {
+		if !seen[FR.ID] {
+			E.NotInTree = append(E.NotInTree, FR.ID)
+		}
+	}
+
+	if len(E.NotInAlignment) > 0 || len(E.NotInTree) > 0 || len(E.Duplicated) > 0 {
+		return []FastaRecord{}, E
+	}
+	return ordered, nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import "io"
+
+// newlineReader converts dos (\r\n) and old Mac (\r) line endings to \n as the
+// input is read, so that the parsers only ever have to deal with \n
+type newlineReader struct {
+	r      io.Reader
+	lastCR bool // the last byte of the previous Read was a \r
+}
+
+func (nl *newlineReader) Read(p []byte) (int, error) {
+	for {
+		n, err := nl.r.Read(p)
+		// the output is never longer than the input, so convert in place
+		out := 0
+		for _, b := range p[:n] {
+			switch {
+			case b == '\n' && nl.lastCR:
+				// the \n of a \r\n whose \r has already been written as a \n
+			case b == '\r':
+				p[out] = '\n'
+				out++
+			default:
+				p[out] = b
+				out++
+			}
+			nl.lastCR = b == '\r'
+		}
+		// don't return 0, nil just because the read was a dropped \n
+		if out > 0 || err != nil || n == 0 {
+			return out, err
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+// ToUpper converts a record's sequence to upper case, in place. Encoded records
+// are left alone, because the encoding does not record case.
+func (FR *FastaRecord) ToUpper() {
+	if FR.encoded {
+		return
+	}
+	for i, b := range FR.Seq {
+		FR.Seq[i] = toUpper(b)
+	}
+}
+
+// ToLower converts a record's sequence to lower case, in place. Encoded records are
+// left alone.
+func (FR *FastaRecord) ToLower() {
+	if FR.encoded {
+		return
+	}
+	for i, b := range FR.Seq {
+		FR.Seq[i] = toLower(b)
+	}
+}
+
+// Normalize standardizes a sequence from another source so that it can be encoded:
+// U becomes T (keeping case), '.' becomes '-', and '*' characters are removed. It
+// works in place, and encoded records (which can't contain these) are left alone.
+func (FR *FastaRecord) Normalize() {
+	if FR.encoded {
+		return
+	}
+	seq := FR.Seq[:0]
+	for _, b := range FR.Seq {
+		switch b {
+		case 'U':
+			b = 'T'
+		case 'u':
+			b = 't'
+		case '.':
+			b = '-'
+		case '*':
+			continue
+		}
+		seq = append(seq, b)
+	}
+	FR.Seq = seq
+}
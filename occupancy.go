@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Occupancy returns, for each alignment column, the number of records with an
+// unambiguous base there (rather than N, a gap or another ambiguity code). The
+// records may be encoded or not, but must all be the same length.
+func Occupancy(aln []FastaRecord) ([]int, error) {
+	if len(aln) == 0 {
+		return []int{}, nil
+	}
+	w := len(aln[0].Seq)
+	occupancy := make([]int, w)
+	for i := range aln {
+		if len(aln[i].Seq) != w {
+			return []int{}, errDifferentWidths
+		}
+		for j := range aln[i].Seq {
+			if aln[i].encodedAt(j)&8 != 0 {
+				occupancy[j]++
+			}
+		}
+	}
+	return occupancy, nil
+}
+
+// LowOccupancy returns the runs of columns where fewer than min records have an
+// unambiguous base, as 0-based, half-open intervals
+func LowOccupancy(occupancy []int, min int) []Interval {
+	runs := make([]Interval, 0)
+	start := -1
+	for i, n := range occupancy {
+		if n < min {
+			if start == -1 {
+				start = i
+			}
+		} else if start != -1 {
+			runs = append(runs, Interval{Start: start, End: i})
+			start = -1
+		}
+	}
+	if start != -1 {
+		runs = append(runs, Interval{Start: start, End: len(occupancy)})
+	}
+	return runs
+}
+
+// WriteLowOccupancyBED writes the low-occupancy runs of columns (see LowOccupancy)
+// as BED lines on chromosome chrom, with the lowest occupancy in each run as the score
+func WriteLowOccupancyBED(w io.Writer, chrom string, occupancy []int, min int) error {
+	bw := bufio.NewWriter(w)
+	for _, iv := range LowOccupancy(occupancy, min) {
+		lowest := occupancy[iv.Start]
+		for _, n := range occupancy[iv.Start:iv.End] {
+			if n < lowest {
+				lowest = n
+			}
+		}
+		if _, err := fmt.Fprintf(bw, "%s\t%d\t%d\tlow_occupancy\t%d\n", chrom, iv.Start, iv.End, lowest); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"math"
+)
+
+var errOligoBases = errors.New("Oligo must contain only A, C, G and T")
+
+// Conditions for the nearest-neighbour melting temperature
+type OligoOptions struct {
+	Na        float64 // the monovalent cation (Na+) concentration, in mM
+	OligoConc float64 // the total oligo concentration, in nM
+}
+
+// DefaultOligoOptions are typical PCR primer conditions
+var DefaultOligoOptions = OligoOptions{Na: 50, OligoConc: 250}
+
+// Properties of a short oligo (e.g. a primer), for QC
+type OligoProperties struct {
+	ID                 string
+	Length             int
+	GC                 float64 // GC percent
+	TmWallace          float64 // 2(A+T) + 4(G+C), in degrees C
+	TmNN               float64 // the nearest-neighbour Tm, in degrees C
+	LongestHomopolymer int
+	HomopolymerBase    byte // the base of the longest homopolymer (the first, if there is a tie)
+}
+
+// SantaLucia (1998) unified nearest-neighbour parameters: the enthalpy (kcal/mol)
+// and entropy (cal/K/mol) of each dinucleotide stack, indexed A, C, G, T. A stack and
+// its reverse complement (e.g. AA and TT) have the same values.
+var nnEnthalpy, nnEntropy = makeNNTables()
+
+func makeNNTables() ([4][4]float64, [4][4]float64) {
+	var dH, dS [4][4]float64
+	params := []struct {
+		stack  string
+		dH, dS float64
+	}{
+		{"AA", -7.9, -22.2}, {"AT", -7.2, -20.4}, {"TA", -7.2, -21.3}, {"CA", -8.5, -22.7},
+		{"GT", -8.4, -22.4}, {"CT", -7.8, -21.0}, {"GA", -8.2, -22.2}, {"CG", -10.6, -27.2},
+		{"GC", -9.8, -24.4}, {"GG", -8.0, -19.9},
+	}
+	for _, p := range params {
+		x, y := baseIndex(encodingArray[p.stack[0]]), baseIndex(encodingArray[p.stack[1]])
+		dH[x][y], dS[x][y] = p.dH, p.dS
+		// the reverse complement stack; with A, C, G, T indices the complement is 3-i
+		dH[3-y][3-x], dS[3-y][3-x] = p.dH, p.dS
+	}
+	return dH, dS
+}
+
+// CalcOligo returns the length, GC content, Wallace and nearest-neighbour melting
+// temperatures, and longest homopolymer of a record (encoded or not), which must
+// contain only A, C, G and T
+func CalcOligo(FR FastaRecord, opts OligoOptions) (OligoProperties, error) {
+
+	OP := OligoProperties{ID: FR.ID, Length: len(FR.Seq)}
+
+	bases := make([]int, len(FR.Seq))
+	for i := range FR.Seq {
+		if bases[i] = baseIndex(FR.encodedAt(i)); bases[i] < 0 {
+			return OligoProperties{}, errOligoBases
+		}
+	}
+	if len(bases) == 0 {
+		return OP, nil
+	}
+
+	bc := countBases(&FR)
+	OP.GC = bc.gcPercent()
+	OP.TmWallace = float64(2*(bc.A+bc.T) + 4*(bc.G+bc.C))
+
+	run := 0
+	for i, b := range bases {
+		if i > 0 && b == bases[i-1] {
+			run++
+		} else {
+			run = 1
+		}
+		if run > OP.LongestHomopolymer {
+			OP.LongestHomopolymer = run
+			OP.HomopolymerBase = acgt[b]
+		}
+	}
+
+	if len(bases) > 1 {
+		OP.TmNN = tmNearestNeighbour(bases, opts)
+	}
+
+	return OP, nil
+}
+
+// tmNearestNeighbour is the two-state melting temperature of an oligo (as base
+// indices) with its perfect complement, from the SantaLucia (1998) parameters with
+// an entropy correction for salt
+func tmNearestNeighbour(bases []int, opts OligoOptions) float64 {
+
+	var dH, dS float64
+	for i := 0; i+1 < len(bases); i++ {
+		dH += nnEnthalpy[bases[i]][bases[i+1]]
+		dS += nnEntropy[bases[i]][bases[i+1]]
+	}
+
+	// initiation, for each end
+	for _, b := range []int{bases[0], bases[len(bases)-1]} {
+		if b == 1 || b == 2 {
+			dH += 0.1
+			dS += -2.8
+		} else {
+			dH += 2.3
+			dS += 4.1
+		}
+	}
+
+	selfComplementary := true
+	for i := range bases {
+		if bases[i] != 3-bases[len(bases)-1-i] {
+			selfComplementary = false
+			break
+		}
+	}
+
+	// the effective strand concentration, in M
+	ct := opts.OligoConc * 1e-9 / 4
+	if selfComplementary {
+		dS += -1.4
+		ct = opts.OligoConc * 1e-9
+	}
+
+	dS += 0.368 * float64(len(bases)-1) * math.Log(opts.Na/1000)
+
+	const R = 1.987 // cal/K/mol
+	return dH*1000/(dS+R*math.Log(ct)) - 273.15
+}
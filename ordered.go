@@ -0,0 +1,128 @@
+package main
+
+import (
+	"io"
+	"sync"
+)
+
+type orderedResult[T any] struct {
+	idx   int
+	value T
+	err   error
+}
+
+// ProcessOrdered reads every record from reader, runs fn on each one using workers
+// goroutines, and passes the results to emit in the same order as the records were
+// read. Each record's Idx is set to its position in the file before fn sees it.
+//
+// Processing stops at the first error (from reading, fn or emit), which is
+// returned once the goroutines have finished. Errors are reported in file order,
+// so every result before the failing record has already been emitted.
+func ProcessOrdered[T any](reader *Reader, workers int, fn func(FastaRecord) (T, error), emit func(T) error) error {
+	return ProcessOrderedWithOptions(reader, workers, StreamOptions{BufferSize: workers}, fn, emit)
+}
+
+// ProcessOrderedWithOptions is ProcessOrdered with control over memory use: the job
+// and result channels are buffered to opts.BufferSize, and no more than
+// opts.MaxInFlight records are held at once (counting records waiting in the
+// channels, being processed, and finished but waiting for an earlier record so that
+// they can be emitted in order). MaxInFlight should be at least workers, or the
+// workers won't all be kept busy.
+func ProcessOrderedWithOptions[T any](reader *Reader, workers int, opts StreamOptions, fn func(FastaRecord) (T, error), emit func(T) error) error {
+
+	if workers < 1 {
+		workers = 1
+	}
+	bufferSize := opts.BufferSize
+	if bufferSize < 0 {
+		bufferSize = 0
+	}
+
+	jobs := make(chan FastaRecord, bufferSize)
+	results := make(chan orderedResult[T], bufferSize)
+	quit := make(chan struct{})
+	limiter := NewInFlightLimiter(opts.MaxInFlight)
+
+	var wg sync.WaitGroup
+
+	// the producer. A read error is sent as the result for the record that couldn't
+	// be read, so that it comes out in order.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		for i := 0; ; i++ {
+			FR, err := reader.Read()
+			if err == io.EOF {
+				return
+			} else if err != nil {
+				select {
+				case results <- orderedResult[T]{idx: i, err: err}:
+				case <-quit:
+				}
+				return
+			}
+			FR.Idx = i
+			if limiter != nil {
+				select {
+				case limiter.slots <- struct{}{}:
+				case <-quit:
+					return
+				}
+			}
+			select {
+			case jobs <- FR:
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for FR := range jobs {
+				value, err := fn(FR)
+				results <- orderedResult[T]{idx: FR.Idx, value: value, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// results arrive in any order, so hold on to them until it is their turn
+	pending := make(map[int]orderedResult[T])
+	next := 0
+	var firstErr error
+
+	for res := range results {
+		if firstErr != nil {
+			// keep draining so that the workers can exit
+			continue
+		}
+		pending[res.idx] = res
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if r.err == nil {
+				limiter.Release()
+				r.err = emit(r.value)
+			}
+			if r.err != nil {
+				firstErr = r.err
+				close(quit)
+				break
+			}
+		}
+	}
+
+	return firstErr
+}
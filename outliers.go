@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"strconv"
+)
+
+// How far one record diverges from the rest of an alignment
+type OutlierScore struct {
+	RecordID      string
+	Sites         int     // columns where the record and at least one other record have an unambiguous base
+	Disagreements int     // of those, columns where the record's base isn't the most common among the others
+	Divergence    float64 // Disagreements / Sites
+	Score         float64 // Divergence with each column weighted by how conserved it is (see FindOutliers)
+	Outlier       bool    // Score is above the threshold
+}
+
+// FindOutliers scores every record in an alignment (encoded or not) by how often its
+// base disagrees with the majority of the other records, as a QC check for
+// contamination or misalignment. Each column is weighted by 1 - H/2, where H is the
+// Shannon entropy (in bits) of the column's unambiguous bases, so disagreeing where
+// the alignment is conserved counts for more than disagreeing at a variable site.
+// Records whose Score is above threshold are flagged as outliers. Only unambiguous
+// bases are compared.
+func FindOutliers(aln []FastaRecord, threshold float64) ([]OutlierScore, error) {
+	sites, err := AlleleFrequencies(aln)
+	if err != nil {
+		return []OutlierScore{}, err
+	}
+
+	weights := make([]float64, len(sites))
+	for i, SF := range sites {
+		H := 0.0
+		for _, c := range SF.Counts {
+			if c > 0 {
+				p := float64(c) / float64(SF.N)
+				H -= p * math.Log2(p)
+			}
+		}
+		weights[i] = 1 - H/2
+	}
+
+	scores := make([]OutlierScore, len(aln))
+	for r := range aln {
+		OS := OutlierScore{RecordID: aln[r].ID}
+		var weighted, total float64
+		for i := range aln[r].Seq {
+			b := baseIndex(aln[r].encodedAt(i))
+			if b < 0 || sites[i].N < 2 {
+				continue
+			}
+			// the majority of the other records, leaving this one out
+			counts := sites[i].Counts
+			counts[b]--
+			most := 0
+			for _, c := range counts {
+				if c > most {
+					most = c
+				}
+			}
+			OS.Sites++
+			total += weights[i]
+			if counts[b] < most {
+				OS.Disagreements++
+				weighted += weights[i]
+			}
+		}
+		if OS.Sites > 0 {
+			OS.Divergence = float64(OS.Disagreements) / float64(OS.Sites)
+		}
+		if total > 0 {
+			OS.Score = weighted / total
+		}
+		OS.Outlier = OS.Score > threshold
+		scores[r] = OS
+	}
+
+	return scores, nil
+}
+
+// WriteOutlierReport writes outlier scores as tsv
+func WriteOutlierReport(w io.Writer, scores []OutlierScore) error {
+	c := csv.NewWriter(w)
+	c.Comma = '\t'
+	if err := c.Write([]string{"id", "sites", "disagreements", "divergence", "score", "outlier"}); err != nil {
+		return err
+	}
+	for _, OS := range scores {
+		err := c.Write([]string{
+			OS.RecordID,
+			strconv.Itoa(OS.Sites),
+			strconv.Itoa(OS.Disagreements),
+			strconv.FormatFloat(OS.Divergence, 'f', 4, 64),
+			strconv.FormatFloat(OS.Score, 'f', 4, 64),
+			strconv.FormatBool(OS.Outlier),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	c.Flush()
+	return c.Error()
+}
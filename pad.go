@@ -0,0 +1,40 @@
+package main
+
+// One record that was padded by PadToWidth
+type PadReport struct {
+	ID             string
+	OriginalLength int
+	Padded         int // the number of characters added
+}
+
+// PadToWidth pads every record shorter than the longest one with trailing copies of
+// fill (usually '-' or 'N', given unencoded), in place, so that a slightly ragged
+// file can be used as an alignment. Encoded records are padded with the encoded
+// fill character. It returns a report of the records that were padded.
+func PadToWidth(records []FastaRecord, fill byte) []PadReport {
+
+	w := 0
+	for _, FR := range records {
+		if len(FR.Seq) > w {
+			w = len(FR.Seq)
+		}
+	}
+
+	report := make([]PadReport, 0)
+	for i := range records {
+		FR := &records[i]
+		if len(FR.Seq) == w {
+			continue
+		}
+		c := fill
+		if FR.encoded {
+			c = encodingArray[fill]
+		}
+		report = append(report, PadReport{ID: FR.ID, OriginalLength: len(FR.Seq), Padded: w - len(FR.Seq)})
+		for len(FR.Seq) < w {
+			FR.Seq = append(FR.Seq, c)
+		}
+	}
+
+	return report
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// One operation of a CIGAR string
+type CigarOp struct {
+	Op     byte
+	Length int
+}
+
+var errBadlyFormedCigar = errors.New("Badly formed CIGAR string")
+
+// ParseCigar splits a CIGAR string into its operations
+func ParseCigar(cigar string) ([]CigarOp, error) {
+	ops := make([]CigarOp, 0)
+	n := 0
+	digits := false
+	for i := 0; i < len(cigar); i++ {
+		c := cigar[i]
+		if c >= '0' && c <= '9' {
+			n = n*10 + int(c-'0')
+			digits = true
+			continue
+		}
+		if !digits || strings.IndexByte("MIDNSHP=X", c) == -1 {
+			return []CigarOp{}, errBadlyFormedCigar
+		}
+		ops = append(ops, CigarOp{Op: c, Length: n})
+		n = 0
+		digits = false
+	}
+	if digits {
+		return []CigarOp{}, errBadlyFormedCigar
+	}
+	return ops, nil
+}
+
+// One line of a PAF file. Coordinates are 0-based and half-open, as in the file.
+// Cigar is taken from the cg:Z: tag, which minimap2 writes when run with -c.
+type PAFRecord struct {
+	QueryName   string
+	QueryLength int
+	QueryStart  int
+	QueryEnd    int
+	Strand      byte
+	TargetName  string
+	TargetLen   int
+	TargetStart int
+	TargetEnd   int
+	Matches     int
+	BlockLength int
+	MapQ        int
+	Cigar       string
+}
+
+var (
+	errBadlyFormedPAF = errors.New("Badly formed PAF line")
+	errNoCigar        = errors.New("PAF line has no cg:Z: CIGAR tag")
+	errCigarMismatch  = errors.New("CIGAR string does not match the sequence coordinates")
+)
+
+// ReadPAF reads every line of a PAF file
+func ReadPAF(r io.Reader) ([]PAFRecord, error) {
+
+	pafs := make([]PAFRecord, 0)
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 256*1024*1024)
+
+	for s.Scan() {
+		line := strings.TrimRight(s.Text(), "\r")
+		if len(line) == 0 {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 12 || len(fields[4]) != 1 {
+			return []PAFRecord{}, errBadlyFormedPAF
+		}
+
+		P := PAFRecord{QueryName: fields[0], Strand: fields[4][0], TargetName: fields[5]}
+		ints := []*int{&P.QueryLength, &P.QueryStart, &P.QueryEnd, nil, nil, &P.TargetLen, &P.TargetStart, &P.TargetEnd, &P.Matches, &P.BlockLength, &P.MapQ}
+		for i, p := range ints {
+			if p == nil {
+				continue
+			}
+			var err error
+			if *p, err = strconv.Atoi(fields[i+1]); err != nil || *p < 0 {
+				return []PAFRecord{}, errBadlyFormedPAF
+			}
+		}
+		for _, tag := range fields[12:] {
+			if strings.HasPrefix(tag, "cg:Z:") {
+				P.Cigar = tag[5:]
+			}
+		}
+
+		pafs = append(pafs, P)
+	}
+
+	if err := s.Err(); err != nil {
+		return []PAFRecord{}, err
+	}
+
+	return pafs, nil
+}
+
+// Apply reconstructs the pairwise alignment that a PAF line describes, returning the
+// gapped target and query over the aligned region only. For minus-strand lines the
+// query is reverse complemented. The records may be encoded or not, and the output
+// keeps their encoding.
+func (P PAFRecord) Apply(query, target FastaRecord) (FastaRecord, FastaRecord, error) {
+
+	if P.Cigar == "" {
+		return FastaRecord{}, FastaRecord{}, errNoCigar
+	}
+	ops, err := ParseCigar(P.Cigar)
+	if err != nil {
+		return FastaRecord{}, FastaRecord{}, err
+	}
+	if P.QueryStart < 0 || P.TargetStart < 0 || P.QueryEnd > len(query.Seq) || P.TargetEnd > len(target.Seq) ||
+		P.QueryStart > P.QueryEnd || P.TargetStart > P.TargetEnd {
+		return FastaRecord{}, FastaRecord{}, errCigarMismatch
+	}
+
+	q := make([]byte, P.QueryEnd-P.QueryStart)
+	copy(q, query.Seq[P.QueryStart:P.QueryEnd])
+	if P.Strand == '-' {
+		reverseComplementBytes(q, query.encoded)
+	}
+	t := target.Seq[P.TargetStart:P.TargetEnd]
+
+	alnOps, err := expandCigar(ops, len(q), len(t))
+	if err != nil {
+		return FastaRecord{}, FastaRecord{}, err
+	}
+
+	alnT, alnQ := applyAlignmentOps(FastaRecord{Seq: t, encoded: target.encoded}, FastaRecord{Seq: q, encoded: query.encoded}, alnOps, 0, 0)
+	alnT.ID, alnT.Description, alnT.idSep, alnT.Idx = target.ID, target.Description, target.idSep, 0
+	alnQ.ID, alnQ.Description, alnQ.idSep, alnQ.Idx = query.ID, query.Description, query.idSep, 0
+
+	return alnT, alnQ, nil
+}
+
+// expandCigar turns CIGAR operations into one M, I or D per alignment column
+// (relative to the target), checking that they consume exactly qLen query bases
+// and tLen target bases. Clips and padding are skipped.
+func expandCigar(ops []CigarOp, qLen, tLen int) ([]byte, error) {
+	alnOps := make([]byte, 0, tLen)
+	qUsed, tUsed := 0, 0
+	for _, op := range ops {
+		var c byte
+		switch op.Op {
+		case 'M', '=', 'X':
+			c = 'M'
+			qUsed += op.Length
+			tUsed += op.Length
+		case 'I':
+			c = 'I'
+			qUsed += op.Length
+		case 'D', 'N':
+			c = 'D'
+			tUsed += op.Length
+		default:
+			continue
+		}
+		for k := 0; k < op.Length; k++ {
+			alnOps = append(alnOps, c)
+		}
+	}
+	if qUsed != qLen || tUsed != tLen {
+		return []byte{}, errCigarMismatch
+	}
+	return alnOps, nil
+}
+
+// PAFToMultiAlign builds a multiple alignment in target coordinates from PAF lines
+// against a single target: one record per query (in the order of queries), the same
+// length as the target, with gaps outside the aligned regions and where the query
+// has deletions. Query insertions are dropped. If a query has more than one PAF
+// line, positions already filled by an earlier line are not overwritten. Lines for
+// other targets are ignored.
+func PAFToMultiAlign(pafs []PAFRecord, queries []FastaRecord, target FastaRecord) ([]FastaRecord, error) {
+
+	byID := make(map[string]int, len(queries))
+	aligned := make([]FastaRecord, len(queries))
+	filled := make([][]bool, len(queries))
+
+	for i, q := range queries {
+		byID[q.ID] = i
+		var gap byte = '-'
+		if q.encoded {
+			gap = 244
+		}
+		seq := make([]byte, len(target.Seq))
+		for j := range seq {
+			seq[j] = gap
+		}
+		aligned[i] = FastaRecord{ID: q.ID, Description: q.Description, idSep: q.idSep, Seq: seq, Score: q.Score, Idx: i, encoded: q.encoded}
+		filled[i] = make([]bool, len(target.Seq))
+	}
+
+	for _, P := range pafs {
+		if P.TargetName != target.ID {
+			continue
+		}
+		i, ok := byID[P.QueryName]
+		if !ok {
+			return []FastaRecord{}, errRecordNotFound
+		}
+		alnT, alnQ, err := P.Apply(queries[i], target)
+		if err != nil {
+			return []FastaRecord{}, err
+		}
+		pos := P.TargetStart
+		for j := range alnT.Seq {
+			if alnT.encodedAt(j) == 244 {
+				continue
+			}
+			if !filled[i][pos] {
+				aligned[i].Seq[pos] = alnQ.Seq[j]
+				filled[i][pos] = true
+			}
+			pos++
+		}
+	}
+
+	return aligned, nil
+}
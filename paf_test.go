@@ -0,0 +1,23 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPAFNegativeCoordinates(t *testing.T) {
+	line := "q\t4\t-1\t3\t+\tt\t4\t0\t4\t4\t4\t60\tcg:Z:4M\n"
+	if _, err := ReadPAF(strings.NewReader(line)); err != errBadlyFormedPAF {
+		t.Errorf("got %v, want errBadlyFormedPAF", err)
+	}
+
+	query, target := FastaRecord{ID: "q", Seq: []byte("ACGT")}, FastaRecord{ID: "t", Seq: []byte("ACGT")}
+	for _, P := range []PAFRecord{
+		{QueryStart: -1, QueryEnd: 3, TargetStart: 0, TargetEnd: 4, Strand: '+', Cigar: "4M"},
+		{QueryStart: 0, QueryEnd: 4, TargetStart: -2, TargetEnd: 2, Strand: '+', Cigar: "4M"},
+	} {
+		if _, _, err := P.Apply(query, target); err != errCigarMismatch {
+			t.Errorf("%+v: got %v, want errCigarMismatch", P, err)
+		}
+	}
+}
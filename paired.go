@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A PairingError reports reads that should be mates but aren't: either their names
+// don't match, or one file (or an interleaved file) ran out of reads first, in
+// which case the missing mate's name is ""
+type PairingError struct {
+	Pair int // the 0-based index of the pair
+	R1   string
+	R2   string
+}
+
+func (E *PairingError) Error() string {
+	switch {
+	case E.R1 == "":
+		return fmt.Sprintf("Read pairing broken at pair %d: %q has no R1 mate", E.Pair, E.R2)
+	case E.R2 == "":
+		return fmt.Sprintf("Read pairing broken at pair %d: %q has no R2 mate", E.Pair, E.R1)
+	}
+	return fmt.Sprintf("Read pairing broken at pair %d: %q and %q are not mates", E.Pair, E.R1, E.R2)
+}
+
+// pairName returns a read's ID without a /1 or /2 suffix, which is what mates share
+func pairName(id string) string {
+	if strings.HasSuffix(id, "/1") || strings.HasSuffix(id, "/2") {
+		return id[:len(id)-2]
+	}
+	return id
+}
+
+// A PairedReader reads mate pairs, either from two files in lockstep or from one
+// interleaved file, checking that each pair's names match
+type PairedReader struct {
+	r1, r2 *FastqReader
+	pair   int
+}
+
+// NewPairedReader reads pairs from separate R1 and R2 files
+func NewPairedReader(f1, f2 io.Reader) *PairedReader {
+	return &PairedReader{r1: NewFastqReader(f1), r2: NewFastqReader(f2)}
+}
+
+// NewInterleavedReader reads pairs from a file in which each R1 is followed by its R2
+func NewInterleavedReader(f io.Reader) *PairedReader {
+	r := NewFastqReader(f)
+	return &PairedReader{r1: r, r2: r}
+}
+
+// Read returns the next pair, or io.EOF once every read has been paired. A
+// *PairingError is returned if the mates' names differ or one is missing.
+func (r *PairedReader) Read() (FastqRecord, FastqRecord, error) {
+	FQ1, err1 := r.r1.Read()
+	if err1 != nil && err1 != io.EOF {
+		return FastqRecord{}, FastqRecord{}, err1
+	}
+	FQ2, err2 := r.r2.Read()
+	if err2 != nil && err2 != io.EOF {
+		return FastqRecord{}, FastqRecord{}, err2
+	}
+
+	switch {
+	case err1 == io.EOF && err2 == io.EOF:
+		return FastqRecord{}, FastqRecord{}, io.EOF
+	case err1 == io.EOF:
+		return FastqRecord{}, FastqRecord{}, &PairingError{Pair: r.pair, R2: FQ2.ID}
+	case err2 == io.EOF:
+		return FastqRecord{}, FastqRecord{}, &PairingError{Pair: r.pair, R1: FQ1.ID}
+	case pairName(FQ1.ID) != pairName(FQ2.ID):
+		return FastqRecord{}, FastqRecord{}, &PairingError{Pair: r.pair, R1: FQ1.ID, R2: FQ2.ID}
+	}
+
+	FQ1.Idx, FQ2.Idx = r.pair, r.pair
+	r.pair++
+	return FQ1, FQ2, nil
+}
+
+// writeFastq writes one record in four-line fastq format
+func writeFastq(w *bufio.Writer, FQ FastqRecord) error {
+	header := FQ.Description
+	if header == "" {
+		header = FQ.ID
+	}
+	_, err := fmt.Fprintf(w, "@%s\n%s\n+\n%s\n", header, FQ.Seq, FQ.Qual)
+	return err
+}
+
+// copyPairs reads every pair from r and passes it to write
+func copyPairs(r *PairedReader, write func(FQ1, FQ2 FastqRecord) error) error {
+	for {
+		FQ1, FQ2, err := r.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err = write(FQ1, FQ2); err != nil {
+			return err
+		}
+	}
+}
+
+// Interleave writes the pairs from separate R1 and R2 files to w as one interleaved
+// file, returning a *PairingError if the files don't pair up
+func Interleave(f1, f2 io.Reader, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	err := copyPairs(NewPairedReader(f1, f2), func(FQ1, FQ2 FastqRecord) error {
+		if err := writeFastq(bw, FQ1); err != nil {
+			return err
+		}
+		return writeFastq(bw, FQ2)
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// Deinterleave splits an interleaved file into separate R1 and R2 files, returning
+// a *PairingError if consecutive reads aren't mates
+func Deinterleave(f io.Reader, w1, w2 io.Writer) error {
+	bw1, bw2 := bufio.NewWriter(w1), bufio.NewWriter(w2)
+	err := copyPairs(NewInterleavedReader(f), func(FQ1, FQ2 FastqRecord) error {
+		if err := writeFastq(bw1, FQ1); err != nil {
+			return err
+		}
+		return writeFastq(bw2, FQ2)
+	})
+	if err != nil {
+		return err
+	}
+	if err = bw1.Flush(); err != nil {
+		return err
+	}
+	return bw2.Flush()
+}
@@ -0,0 +1,639 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A ScoringMatrix holds the score for aligning any two residues. It is indexed by
+// uppercase bytes, so the same type serves nucleotide and protein alignments.
+type ScoringMatrix [256][256]int
+
+// Set sets the score for aligning a with b (and b with a), ignoring case
+func (SM *ScoringMatrix) Set(a, b byte, score int) {
+	a, b = toUpper(a), toUpper(b)
+	SM[a][b] = score
+	SM[b][a] = score
+}
+
+func toUpper(b byte) byte {
+	if b >= 'a' && b <= 'z' {
+		return b - ('a' - 'A')
+	}
+	return b
+}
+
+func toLower(b byte) byte {
+	if b >= 'A' && b <= 'Z' {
+		return b + ('a' - 'A')
+	}
+	return b
+}
+
+// NewNucleotideMatrix returns a matrix that scores match for identical unambiguous
+// bases and mismatch for everything else, except that ambiguity codes (including N)
+// score 0 against any base they could represent
+func NewNucleotideMatrix(match, mismatch int) *ScoringMatrix {
+	SM := &ScoringMatrix{}
+	nucs := "ACGTRYSWKMBDHVN"
+	for i := 0; i < len(nucs); i++ {
+		for j := 0; j < len(nucs); j++ {
+			ea, eb := encodingArray[nucs[i]], encodingArray[nucs[j]]
+			switch {
+			case ea == eb && ea&8 == 8:
+				SM[nucs[i]][nucs[j]] = match
+			case ea&eb >= 16:
+				SM[nucs[i]][nucs[j]] = 0
+			default:
+				SM[nucs[i]][nucs[j]] = mismatch
+			}
+		}
+	}
+	SM['U'] = SM['T']
+	for i := range SM {
+		SM[i]['U'] = SM[i]['T']
+	}
+	return SM
+}
+
+var errBadlyFormedMatrix = errors.New("Badly formed scoring matrix")
+
+// ReadScoringMatrix parses a scoring matrix in the NCBI layout (as used for the
+// BLOSUM and PAM files): '#' comment lines, a header line of residues, then one
+// row per residue beginning with that residue
+func ReadScoringMatrix(r io.Reader) (*ScoringMatrix, error) {
+	SM := &ScoringMatrix{}
+	s := bufio.NewScanner(r)
+	var columns []string
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if columns == nil {
+			columns = fields
+			continue
+		}
+		if len(fields) != len(columns)+1 || len(fields[0]) != 1 {
+			return nil, errBadlyFormedMatrix
+		}
+		for j, f := range fields[1:] {
+			score, err := strconv.Atoi(f)
+			if err != nil || len(columns[j]) != 1 {
+				return nil, errBadlyFormedMatrix
+			}
+			a, b := toUpper(fields[0][0]), toUpper(columns[j][0])
+			SM[a][b] = score
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if columns == nil {
+		return nil, errBadlyFormedMatrix
+	}
+	return SM, nil
+}
+
+// Scoring parameters for pairwise alignment. A gap of length k scores
+// -(GapOpen + (k - 1) * GapExtend).
+type AlignParams struct {
+	Matrix    *ScoringMatrix
+	GapOpen   int
+	GapExtend int
+}
+
+// DefaultAlignParams returns nucleotide parameters similar to blastn's defaults
+func DefaultAlignParams() AlignParams {
+	return AlignParams{Matrix: NewNucleotideMatrix(2, -3), GapOpen: 5, GapExtend: 2}
+}
+
+// The result of a pairwise alignment. A and B are the aligned (gapped) records,
+// which for a local alignment only cover the aligned region. The Start/End fields
+// give the 0-based, half-open extent of that region in the original sequences.
+// Cigar describes B relative to A (I = bases in B only, D = bases in A only), with
+// soft clips (S) for any unaligned ends of B in a local alignment.
+type PairwiseAlignment struct {
+	A      FastaRecord
+	B      FastaRecord
+	Score  int
+	Cigar  string
+	AStart int
+	AEnd   int
+	BStart int
+	BEnd   int
+}
+
+// the score used for impossible states, low enough never to be chosen but with room
+// to subtract gap penalties without wrapping, even where int is 32 bits
+const negInf = math.MinInt32 / 2
+
+// traceback states
+const (
+	tbM = 0
+	tbX = 1 // residue in A against a gap
+	tbY = 2 // residue in B against a gap
+)
+
+// alignments with no more than this many DP cells are traced back from a full
+// matrix; bigger ones are split in half first (see align)
+const alignBaseCells = 1 << 16
+
+// AlignGlobal aligns two records end to end (Needleman-Wunsch with affine gaps,
+// after Gotoh). The records may be encoded or not; the aligned records keep the
+// encoding of their inputs. The alignment is found in linear space (Myers and
+// Miller 1988), so memory use is proportional to the sum of the lengths, and time
+// to their product. GapOpen is treated as GapExtend if it is smaller.
+func AlignGlobal(a, b FastaRecord, params AlignParams) PairwiseAlignment {
+	return pairwiseAlign(a, b, params, false)
+}
+
+// AlignLocal finds the best-scoring local alignment of two records (Smith-Waterman
+// with affine gaps). See AlignGlobal.
+func AlignLocal(a, b FastaRecord, params AlignParams) PairwiseAlignment {
+	return pairwiseAlign(a, b, params, true)
+}
+
+// residue returns the uppercase residue at position i, whether or not the record is encoded
+func (FR *FastaRecord) residue(i int) byte {
+	if FR.encoded {
+		return decodingArray[FR.Seq[i]]
+	}
+	return toUpper(FR.Seq[i])
+}
+
+// An aligner holds the state of one pairwise alignment. A gap of length k costs
+// g + k*h.
+type aligner struct {
+	a, b       []byte // the uppercase residues
+	arev, brev []byte // and reversed
+	SM         *ScoringMatrix
+	g, h       int
+	ops        []byte // the CIGAR operations found so far, in order
+
+	// the last rows of the forward and reverse passes, and scratch rows for them
+	cc, dd, rr, ss   []int
+	rowM, rowX, rowY []int
+}
+
+func newAligner(a, b FastaRecord, params AlignParams) *aligner {
+	al := &aligner{SM: params.Matrix, h: params.GapExtend, g: params.GapOpen - params.GapExtend}
+	if al.g < 0 {
+		al.g = 0
+	}
+	al.a, al.arev = make([]byte, len(a.Seq)), make([]byte, len(a.Seq))
+	for i := range a.Seq {
+		al.a[i] = a.residue(i)
+		al.arev[len(a.Seq)-1-i] = al.a[i]
+	}
+	al.b, al.brev = make([]byte, len(b.Seq)), make([]byte, len(b.Seq))
+	for j := range b.Seq {
+		al.b[j] = b.residue(j)
+		al.brev[len(b.Seq)-1-j] = al.b[j]
+	}
+	rows := make([]int, 7*(len(b.Seq)+1))
+	for k, row := range []*[]int{&al.cc, &al.dd, &al.rr, &al.ss, &al.rowM, &al.rowX, &al.rowY} {
+		*row = rows[k*(len(b.Seq)+1) : (k+1)*(len(b.Seq)+1)]
+	}
+	return al
+}
+
+func pairwiseAlign(a, b FastaRecord, params AlignParams, local bool) PairwiseAlignment {
+
+	n, m := len(a.Seq), len(b.Seq)
+	al := newAligner(a, b, params)
+
+	aStart, aEnd, bStart, bEnd := 0, n, 0, m
+	if local {
+		var best int
+		best, aEnd, bEnd = al.localEnd()
+		if best < 0 {
+			aEnd, bEnd = 0, 0
+		}
+		aStart, bStart = al.localStart(aEnd, bEnd)
+	}
+	al.ops = make([]byte, 0, n+m)
+	al.align(aStart, aEnd, bStart, bEnd, al.g, al.g)
+
+	PA := PairwiseAlignment{Score: al.score(aStart, bStart), AStart: aStart, AEnd: aEnd, BStart: bStart, BEnd: bEnd}
+	PA.A, PA.B = applyAlignmentOps(a, b, al.ops, aStart, bStart)
+	PA.Cigar = cigarString(al.ops, bStart, m-bEnd)
+
+	return PA
+}
+
+// score returns the score of the operations found, beginning at aStart and bStart
+func (al *aligner) score(aStart, bStart int) int {
+	score := 0
+	i, j := aStart, bStart
+	for k, op := range al.ops {
+		switch op {
+		case 'M':
+			score += al.SM[al.a[i]][al.b[j]]
+			i++
+			j++
+			continue
+		case 'D':
+			i++
+		case 'I':
+			j++
+		}
+		score -= al.h
+		if k == 0 || al.ops[k-1] != op {
+			score -= al.g
+		}
+	}
+	return score
+}
+
+// lastRow computes the scores of aligning all of a with each prefix b[:j]: the best
+// (in cc[j]) and the best that ends with a deletion, i.e. a residue of a against a
+// gap (in dd[j]). A deletion at the very start costs tb to open rather than g.
+func (al *aligner) lastRow(a, b []byte, tb int, cc, dd []int) {
+
+	m := len(b)
+	M, X, Y := al.rowM[:m+1], al.rowX[:m+1], al.rowY[:m+1]
+	open := al.g + al.h
+
+	M[0], X[0], Y[0] = 0, negInf, negInf
+	for j := 1; j <= m; j++ {
+		M[j], X[j], Y[j] = negInf, negInf, -(al.g + j*al.h)
+	}
+
+	for i := 1; i <= len(a); i++ {
+		// the diagonal predecessors, before they are overwritten
+		dM, dX, dY := M[0], X[0], Y[0]
+		M[0], X[0], Y[0] = negInf, -(tb + i*al.h), negInf
+		row := &al.SM[a[i-1]]
+
+		for j := 1; j <= m; j++ {
+			d := dM
+			if dX > d {
+				d = dX
+			}
+			if dY > d {
+				d = dY
+			}
+			dM, dX, dY = M[j], X[j], Y[j]
+
+			x := M[j] - open
+			if X[j]-al.h > x {
+				x = X[j] - al.h
+			}
+			if Y[j]-open > x {
+				x = Y[j] - open
+			}
+
+			M[j] = d + row[b[j-1]]
+			X[j] = x
+
+			y := M[j-1] - open
+			if Y[j-1]-al.h > y {
+				y = Y[j-1] - al.h
+			}
+			if X[j-1]-open > y {
+				y = X[j-1] - open
+			}
+			Y[j] = y
+		}
+	}
+
+	for j := 0; j <= m; j++ {
+		cc[j], dd[j] = M[j], X[j]
+		if X[j] > cc[j] {
+			cc[j] = X[j]
+		}
+		if Y[j] > cc[j] {
+			cc[j] = Y[j]
+		}
+	}
+}
+
+// align appends the operations of an optimal global alignment of a[a0:a1] with
+// b[b0:b1] to al.ops, where a deletion at the start costs tb to open and one at
+// the end costs te (0 if it continues a deletion in a neighbouring part of the
+// alignment, g if not). Big problems are split at the middle row of a into two
+// halves, at the column where the forward and reverse scores meet best (Myers and
+// Miller 1988), so only a few rows of scores are kept at once.
+func (al *aligner) align(a0, a1, b0, b1, tb, te int) {
+
+	n, m := a1-a0, b1-b0
+	if n <= 1 || int64(n+1)*int64(m+1) <= alignBaseCells {
+		al.gotoh(a0, a1, b0, b1, tb, te)
+		return
+	}
+
+	mid := a0 + n/2
+	N, Mb := len(al.a), len(al.b)
+	al.lastRow(al.a[a0:mid], al.b[b0:b1], tb, al.cc, al.dd)
+	al.lastRow(al.arev[N-a1:N-mid], al.brev[Mb-b1:Mb-b0], te, al.rr, al.ss)
+
+	// either the alignment passes through (mid, j) between operations, or a deletion
+	// runs across the middle, which is only charged one opening
+	best, bestJ, across := negInf, 0, false
+	for j := 0; j <= m; j++ {
+		if s := al.cc[j] + al.rr[m-j]; s > best {
+			best, bestJ, across = s, j, false
+		}
+		if s := al.dd[j] + al.ss[m-j] + al.g; s > best {
+			best, bestJ, across = s, j, true
+		}
+	}
+
+	if !across {
+		al.align(a0, mid, b0, b0+bestJ, tb, al.g)
+		al.align(mid, a1, b0+bestJ, b1, al.g, te)
+		return
+	}
+	al.align(a0, mid-1, b0, b0+bestJ, tb, 0)
+	al.ops = append(al.ops, 'D', 'D')
+	al.align(mid+1, a1, b0+bestJ, b1, 0, te)
+}
+
+// gotoh appends the operations of an optimal global alignment of a[a0:a1] with
+// b[b0:b1], as align, from the full traceback matrix with two bits per matrix per cell
+func (al *aligner) gotoh(a0, a1, b0, b1, tb, te int) {
+
+	a, b := al.a[a0:a1], al.b[b0:b1]
+	n, m := len(a), len(b)
+	open := al.g + al.h
+
+	prevM, prevX, prevY := make([]int, m+1), make([]int, m+1), make([]int, m+1)
+	curM, curX, curY := make([]int, m+1), make([]int, m+1), make([]int, m+1)
+	tb2 := make([]byte, (n+1)*(m+1))
+
+	prevM[0], prevX[0], prevY[0] = 0, negInf, negInf
+	for j := 1; j <= m; j++ {
+		prevM[j], prevX[j], prevY[j] = negInf, negInf, -(al.g + j*al.h)
+	}
+
+	for i := 1; i <= n; i++ {
+		curM[0], curX[0], curY[0] = negInf, -(tb + i*al.h), negInf
+		row := &al.SM[a[i-1]]
+
+		for j := 1; j <= m; j++ {
+			var t byte
+
+			// M: residues aligned to each other
+			s, from := prevM[j-1], tbM
+			if prevX[j-1] > s {
+				s, from = prevX[j-1], tbX
+			}
+			if prevY[j-1] > s {
+				s, from = prevY[j-1], tbY
+			}
+			curM[j] = s + row[b[j-1]]
+			t |= byte(from)
+
+			// X: gap in B
+			s, from = prevM[j]-open, tbM
+			if prevX[j]-al.h > s {
+				s, from = prevX[j]-al.h, tbX
+			}
+			if prevY[j]-open > s {
+				s, from = prevY[j]-open, tbY
+			}
+			curX[j] = s
+			t |= byte(from) << 2
+
+			// Y: gap in A
+			s, from = curM[j-1]-open, tbM
+			if curY[j-1]-al.h > s {
+				s, from = curY[j-1]-al.h, tbY
+			}
+			if curX[j-1]-open > s {
+				s, from = curX[j-1]-open, tbX
+			}
+			curY[j] = s
+			t |= byte(from) << 4
+
+			tb2[i*(m+1)+j] = t
+		}
+
+		prevM, curM = curM, prevM
+		prevX, curX = curX, prevX
+		prevY, curY = curY, prevY
+	}
+
+	// a deletion at the end is refunded its opening if it continues past the end
+	best, state := prevM[m], tbM
+	if prevX[m]+al.g-te > best {
+		best, state = prevX[m]+al.g-te, tbX
+	}
+	if prevY[m] > best {
+		state = tbY
+	}
+
+	// trace back from the end, collecting CIGAR operations in reverse
+	start := len(al.ops)
+	i, j := n, m
+	for i > 0 || j > 0 {
+		// the first row and column are all gaps
+		if i == 0 {
+			state = tbY
+		} else if j == 0 {
+			state = tbX
+		}
+		t := tb2[i*(m+1)+j]
+		switch state {
+		case tbM:
+			al.ops = append(al.ops, 'M')
+			state = int(t & 3)
+			i--
+			j--
+		case tbX:
+			al.ops = append(al.ops, 'D')
+			state = int(t>>2) & 3
+			i--
+		case tbY:
+			al.ops = append(al.ops, 'I')
+			state = int(t>>4) & 3
+			j--
+		}
+	}
+	ops := al.ops[start:]
+	for l, r := 0, len(ops)-1; l < r; l, r = l+1, r-1 {
+		ops[l], ops[r] = ops[r], ops[l]
+	}
+}
+
+// localEnd finds where the best local alignment ends, and its score, keeping one
+// row of scores at a time
+func (al *aligner) localEnd() (best, aEnd, bEnd int) {
+
+	m := len(al.b)
+	M, X, Y := al.rowM, al.rowX, al.rowY
+	open := al.g + al.h
+	for j := 0; j <= m; j++ {
+		M[j], X[j], Y[j] = negInf, negInf, negInf
+	}
+	best = negInf
+
+	for i := 1; i <= len(al.a); i++ {
+		dM, dX, dY := M[0], X[0], Y[0]
+		row := &al.SM[al.a[i-1]]
+
+		for j := 1; j <= m; j++ {
+			d := dM
+			if dX > d {
+				d = dX
+			}
+			if dY > d {
+				d = dY
+			}
+			if d < 0 {
+				d = 0
+			}
+			dM, dX, dY = M[j], X[j], Y[j]
+
+			x := M[j] - open
+			if X[j]-al.h > x {
+				x = X[j] - al.h
+			}
+			if Y[j]-open > x {
+				x = Y[j] - open
+			}
+
+			M[j] = d + row[al.b[j-1]]
+			X[j] = x
+
+			y := M[j-1] - open
+			if Y[j-1]-al.h > y {
+				y = Y[j-1] - al.h
+			}
+			if X[j-1]-open > y {
+				y = X[j-1] - open
+			}
+			Y[j] = y
+
+			if M[j] > best {
+				best, aEnd, bEnd = M[j], i, j
+			}
+		}
+	}
+
+	return best, aEnd, bEnd
+}
+
+// localStart finds where the best local alignment ending at aEnd, bEnd begins, by
+// aligning the reversed prefixes from that point and finding the best place to stop
+func (al *aligner) localStart(aEnd, bEnd int) (aStart, bStart int) {
+
+	a := al.arev[len(al.a)-aEnd:]
+	b := al.brev[len(al.b)-bEnd:]
+	m := len(b)
+	M, X, Y := al.rowM[:m+1], al.rowX[:m+1], al.rowY[:m+1]
+	open := al.g + al.h
+
+	M[0], X[0], Y[0] = 0, negInf, negInf
+	for j := 1; j <= m; j++ {
+		M[j], X[j], Y[j] = negInf, negInf, negInf
+	}
+	best, bestI, bestJ := 0, 0, 0
+
+	for i := 1; i <= len(a); i++ {
+		dM, dX, dY := M[0], X[0], Y[0]
+		M[0], X[0], Y[0] = negInf, negInf, negInf
+		row := &al.SM[a[i-1]]
+
+		for j := 1; j <= m; j++ {
+			d := dM
+			if dX > d {
+				d = dX
+			}
+			if dY > d {
+				d = dY
+			}
+			dM, dX, dY = M[j], X[j], Y[j]
+
+			x := M[j] - open
+			if X[j]-al.h > x {
+				x = X[j] - al.h
+			}
+			if Y[j]-open > x {
+				x = Y[j] - open
+			}
+
+			M[j] = d + row[b[j-1]]
+			X[j] = x
+
+			y := M[j-1] - open
+			if Y[j-1]-al.h > y {
+				y = Y[j-1] - al.h
+			}
+			if X[j-1]-open > y {
+				y = X[j-1] - open
+			}
+			Y[j] = y
+
+			if M[j] > best || bestI == 0 {
+				best, bestI, bestJ = M[j], i, j
+			}
+		}
+	}
+
+	return aEnd - bestI, bEnd - bestJ
+}
+
+// applyAlignmentOps builds the gapped records described by ops, beginning at
+// aStart and bStart in the original records
+func applyAlignmentOps(a, b FastaRecord, ops []byte, aStart, bStart int) (FastaRecord, FastaRecord) {
+
+	gapA, gapB := byte('-'), byte('-')
+	if a.encoded {
+		gapA = 244
+	}
+	if b.encoded {
+		gapB = 244
+	}
+
+	seqA := make([]byte, 0, len(ops))
+	seqB := make([]byte, 0, len(ops))
+	i, j := aStart, bStart
+	for _, op := range ops {
+		switch op {
+		case 'M':
+			seqA = append(seqA, a.Seq[i])
+			seqB = append(seqB, b.Seq[j])
+			i++
+			j++
+		case 'D':
+			seqA = append(seqA, a.Seq[i])
+			seqB = append(seqB, gapB)
+			i++
+		case 'I':
+			seqA = append(seqA, gapA)
+			seqB = append(seqB, b.Seq[j])
+			j++
+		}
+	}
+
+	alnA := FastaRecord{ID: a.ID, Description: a.Description, idSep: a.idSep, Seq: seqA, Score: a.Score, encoded: a.encoded}
+	alnB := FastaRecord{ID: b.ID, Description: b.Description, idSep: b.idSep, Seq: seqB, Score: b.Score, Idx: 1, encoded: b.encoded}
+	return alnA, alnB
+}
+
+// cigarString run-length encodes ops, with soft clips for the unaligned ends of the query
+func cigarString(ops []byte, clipStart, clipEnd int) string {
+	var sb strings.Builder
+	if clipStart > 0 {
+		sb.WriteString(strconv.Itoa(clipStart) + "S")
+	}
+	for k := 0; k < len(ops); {
+		l := k + 1
+		for l < len(ops) && ops[l] == ops[k] {
+			l++
+		}
+		sb.WriteString(strconv.Itoa(l - k))
+		sb.WriteByte(ops[k])
+		k = l
+	}
+	if clipEnd > 0 {
+		sb.WriteString(strconv.Itoa(clipEnd) + "S")
+	}
+	return sb.String()
+}
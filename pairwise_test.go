@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestAlignGlobalKnown(t *testing.T) {
+	params := DefaultAlignParams()
+	PA := AlignGlobal(FastaRecord{ID: "a", Seq: []byte("ACGTACGTAC")}, FastaRecord{ID: "b", Seq: []byte("ACGTCGTAC")}, params)
+	if PA.Cigar != "4M1D5M" || PA.Score != 9*2-5 {
+		t.Errorf("got %s, score %d", PA.Cigar, PA.Score)
+	}
+	if string(PA.B.Seq) != "ACGT-CGTAC" {
+		t.Errorf("got %s", PA.B.Seq)
+	}
+}
+
+func TestAlignLinearSpace(t *testing.T) {
+	// far too big for a full traceback matrix to be cheap, so this goes through the
+	// divide and conquer path
+	rng := rand.New(rand.NewSource(1))
+	a := make([]byte, 20000)
+	for i := range a {
+		a[i] = "ACGT"[rng.Intn(4)]
+	}
+	b := append(append(append([]byte{}, a[:9000]...), "TTTTTTTTTT"...), a[9100:]...)
+
+	params := DefaultAlignParams()
+	PA := AlignGlobal(FastaRecord{ID: "a", Seq: a}, FastaRecord{ID: "b", Seq: b}, params)
+	if !bytes.Equal(bytes.ReplaceAll(PA.A.Seq, []byte("-"), nil), a) || !bytes.Equal(bytes.ReplaceAll(PA.B.Seq, []byte("-"), nil), b) {
+		t.Fatal("aligned records don't hold the inputs")
+	}
+	if PA.Score < 2*(len(b)-10)-params.GapOpen-89*params.GapExtend-30 {
+		t.Errorf("score %d is too low", PA.Score)
+	}
+
+	local := AlignLocal(FastaRecord{ID: "a", Seq: a[5000:6000]}, FastaRecord{ID: "b", Seq: b}, params)
+	if local.Score != 2000 || local.BStart != 5000 || local.BEnd != 6000 || local.Cigar != "5000S1000M13910S" {
+		t.Errorf("got score %d, %d-%d, %s", local.Score, local.BStart, local.BEnd, local.Cigar)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+// An alignment with its identical columns collapsed into unique site patterns, as
+// used by phylogenetic likelihood programs
+type SitePatterns struct {
+	Records []FastaRecord // the alignment's records, with one column per pattern
+	Weights []int         // the number of original columns with each pattern
+	Index   []int         // the pattern of each original column
+}
+
+// CompressPatterns collapses the identical columns of an alignment (encoded or not)
+// into unique site patterns, in the order that each is first seen, along with how
+// many columns each one stands for. Columns are compared as uppercase, decoded text,
+// so case doesn't matter. The records keep their IDs and encoding.
+func CompressPatterns(aln []FastaRecord) (SitePatterns, error) {
+	if len(aln) == 0 {
+		return SitePatterns{Records: []FastaRecord{}, Weights: []int{}, Index: []int{}}, nil
+	}
+	w := len(aln[0].Seq)
+	for i := range aln {
+		if len(aln[i].Seq) != w {
+			return SitePatterns{}, errDifferentWidths
+		}
+	}
+
+	SP := SitePatterns{Weights: make([]int, 0), Index: make([]int, w)}
+	seen := make(map[string]int)
+	columns := make([]int, 0)
+	key := make([]byte, len(aln))
+
+	for c := 0; c < w; c++ {
+		for i := range aln {
+			key[i] = aln[i].encodedAt(c)
+		}
+		p, ok := seen[string(key)]
+		if !ok {
+			p = len(columns)
+			seen[string(key)] = p
+			columns = append(columns, c)
+			SP.Weights = append(SP.Weights, 0)
+		}
+		SP.Weights[p]++
+		SP.Index[c] = p
+	}
+
+	SP.Records = selectColumns(aln, columns)
+	return SP, nil
+}
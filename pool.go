@@ -0,0 +1,68 @@
+package main
+
+import "sync"
+
+// Records (and in particular their sequence buffers) are recycled through this pool
+// by GetRecord and PutRecord, by Readers with WithRecordPool, and by Writers with
+// Recycle set, so that a steady-state streaming pipeline reuses the same few
+// buffers instead of allocating a new one for every record.
+var recordPool = sync.Pool{
+	New: func() any { return new(FastaRecord) },
+}
+
+// GetRecord returns an empty record from the pool. Its Seq has length 0 but may have
+// capacity left over from an earlier record.
+func GetRecord() *FastaRecord {
+	FR := recordPool.Get().(*FastaRecord)
+	seq := FR.Seq[:0]
+	*FR = FastaRecord{Seq: seq}
+	return FR
+}
+
+// PutRecord returns a record to the pool. Neither FR nor its Seq may be used
+// afterwards.
+func PutRecord(FR *FastaRecord) {
+	recordPool.Put(FR)
+}
+
+// takeSeq returns a sequence buffer from the pool, or nil if the pooled record had
+// none. The record itself goes straight back.
+func takeSeq() []byte {
+	FR := recordPool.Get().(*FastaRecord)
+	seq := FR.Seq[:0]
+	FR.Seq = nil
+	recordPool.Put(FR)
+	return seq
+}
+
+// recycleSeq returns a sequence buffer to the pool
+func recycleSeq(seq []byte) {
+	if cap(seq) == 0 {
+		return
+	}
+	FR := recordPool.Get().(*FastaRecord)
+	*FR = FastaRecord{Seq: seq[:0]}
+	recordPool.Put(FR)
+}
+
+// WithRecordPool makes the Reader take the buffer for each sequence from the record
+// pool. Pair it with PutRecord, or a Writer with Recycle set, to hand the buffers
+// back when the records are finished with.
+func WithRecordPool() ReaderOption {
+	return func(r *Reader) {
+		r.pooled = true
+	}
+}
+
+// newSeqBuffer returns an empty buffer for the next sequence
+func (r *Reader) newSeqBuffer() []byte {
+	if r.pooled {
+		if seq := takeSeq(); cap(seq) > 0 && cap(seq) >= r.seqCapacity {
+			return seq
+		}
+	}
+	if r.seqCapacity > 0 {
+		return make([]byte, 0, r.seqCapacity)
+	}
+	return nil
+}
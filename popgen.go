@@ -0,0 +1,110 @@
+package main
+
+// Counts of the unambiguous bases in one alignment column, in the order A, C, G, T
+type SiteFrequencies struct {
+	Pos    int // 0-based alignment column
+	Counts [4]int
+	N      int // the number of records with an unambiguous base in this column
+}
+
+// Frequency returns the frequency of base (one of 'A', 'C', 'G' or 'T') among the
+// records that have an unambiguous base in this column
+func (SF SiteFrequencies) Frequency(base byte) float64 {
+	i := baseIndex(encodingArray[base])
+	if i < 0 || SF.N == 0 {
+		return 0
+	}
+	return float64(SF.Counts[i]) / float64(SF.N)
+}
+
+// Segregating reports whether more than one base is seen in this column
+func (SF SiteFrequencies) Segregating() bool {
+	seen := 0
+	for _, c := range SF.Counts {
+		if c > 0 {
+			seen++
+		}
+	}
+	return seen > 1
+}
+
+// heterozygosity is the probability that two records drawn without replacement
+// differ at this site, n / (n - 1) * (1 - sum(p^2))
+func (SF SiteFrequencies) heterozygosity() float64 {
+	if SF.N < 2 {
+		return 0
+	}
+	sumSq := 0.0
+	for _, c := range SF.Counts {
+		p := float64(c) / float64(SF.N)
+		sumSq += p * p
+	}
+	n := float64(SF.N)
+	return n / (n - 1) * (1 - sumSq)
+}
+
+// AlleleFrequencies counts the unambiguous bases in every column of an alignment
+// (encoded or not). Ambiguity codes, Ns and gaps are treated as missing data.
+func AlleleFrequencies(records []FastaRecord) ([]SiteFrequencies, error) {
+
+	if len(records) == 0 {
+		return []SiteFrequencies{}, nil
+	}
+	w := len(records[0].Seq)
+
+	sites := make([]SiteFrequencies, w)
+	for i := range sites {
+		sites[i].Pos = i
+	}
+
+	// records in the outer loop so that each sequence is read in order
+	for _, FR := range records {
+		if len(FR.Seq) != w {
+			return []SiteFrequencies{}, errDifferentWidths
+		}
+		for i := range FR.Seq {
+			if b := baseIndex(FR.encodedAt(i)); b >= 0 {
+				sites[i].Counts[b]++
+				sites[i].N++
+			}
+		}
+	}
+
+	return sites, nil
+}
+
+// Population-genetic summaries of an alignment
+type DiversityStats struct {
+	Sites                      int     // columns where at least two records have an unambiguous base
+	SegregatingSites           int     // columns with more than one base
+	AveragePairwiseDifferences float64 // the expected number of differences between two records
+	Pi                         float64 // nucleotide diversity: AveragePairwiseDifferences per site
+}
+
+// NucleotideDiversity calculates the number of segregating sites and the nucleotide
+// diversity of an alignment. Missing data are handled site by site: each column
+// contributes the proportion of pairs of records with a base there that differ.
+func NucleotideDiversity(records []FastaRecord) (DiversityStats, error) {
+
+	sites, err := AlleleFrequencies(records)
+	if err != nil {
+		return DiversityStats{}, err
+	}
+
+	var DS DiversityStats
+	for _, SF := range sites {
+		if SF.N < 2 {
+			continue
+		}
+		DS.Sites++
+		if SF.Segregating() {
+			DS.SegregatingSites++
+			DS.AveragePairwiseDifferences += SF.heterozygosity()
+		}
+	}
+	if DS.Sites > 0 {
+		DS.Pi = DS.AveragePairwiseDifferences / float64(DS.Sites)
+	}
+
+	return DS, nil
+}
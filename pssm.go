@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// A position-specific scoring matrix for nucleotides, with one column per alignment
+// column and rows in the order A, C, G, T
+type PSSM struct {
+	Counts     [][4]float64 // observed counts
+	LogOdds    [][4]float64 // log2(frequency with pseudocounts / background)
+	Background [4]float64
+}
+
+var errEmptyProfile = errors.New("Cannot build a profile from an empty alignment")
+
+// NewPSSM builds a profile from an alignment (encoded or not). Ambiguity codes are
+// split evenly between the bases they represent; N and gaps are ignored. The
+// pseudocount is added to every cell before converting to frequencies, and the
+// log-odds are against a uniform background.
+func NewPSSM(records []FastaRecord, pseudocount float64) (*PSSM, error) {
+
+	if len(records) == 0 {
+		return nil, errEmptyProfile
+	}
+	w := len(records[0].Seq)
+
+	P := &PSSM{Counts: make([][4]float64, w), LogOdds: make([][4]float64, w)}
+
+	for _, FR := range records {
+		if len(FR.Seq) != w {
+			return nil, errDifferentWidths
+		}
+		for i := range FR.Seq {
+			e := FR.encodedAt(i)
+			if e == 240 || e == 244 || e == 242 || e == 0 {
+				continue
+			}
+			bases := 0
+			for _, bit := range []byte{128, 32, 64, 16} {
+				if e&bit != 0 {
+					bases++
+				}
+			}
+			for b, bit := range []byte{128, 32, 64, 16} {
+				if e&bit != 0 {
+					P.Counts[i][b] += 1 / float64(bases)
+				}
+			}
+		}
+	}
+
+	P.Background = [4]float64{0.25, 0.25, 0.25, 0.25}
+
+	for i := range P.Counts {
+		colSum := 0.0
+		for b := range P.Counts[i] {
+			colSum += P.Counts[i][b] + pseudocount
+		}
+		for b := range P.Counts[i] {
+			if colSum == 0 {
+				continue
+			}
+			f := (P.Counts[i][b] + pseudocount) / colSum
+			P.LogOdds[i][b] = math.Log2(f / P.Background[b])
+		}
+	}
+
+	return P, nil
+}
+
+// Width is the number of columns in the profile
+func (P *PSSM) Width() int {
+	return len(P.LogOdds)
+}
+
+// ScoreAt scores the profile against a record (encoded or not) starting at pos. A
+// base that is not A, C, G or T scores the mean of the bases it could be, or 0 for N.
+func (P *PSSM) ScoreAt(FR FastaRecord, pos int) float64 {
+	score := 0.0
+	for i := range P.LogOdds {
+		e := FR.encodedAt(pos + i)
+		if b := baseIndex(e); b >= 0 {
+			score += P.LogOdds[i][b]
+			continue
+		}
+		if e == 240 || e == 242 || e == 244 || e == 0 {
+			continue
+		}
+		sum, n := 0.0, 0
+		for b, bit := range []byte{128, 32, 64, 16} {
+			if e&bit != 0 {
+				sum += P.LogOdds[i][b]
+				n++
+			}
+		}
+		score += sum / float64(n)
+	}
+	return score
+}
+
+// One match of a profile against a sequence
+type PSSMHit struct {
+	Pos    int  // 0-based start in the sequence
+	Strand byte // '+' or '-'
+	Score  float64
+}
+
+// Scan scores the profile at every position of a record on both strands, and
+// returns the best n hits (or all of them if n < 1) in descending order of score.
+// For minus-strand hits, Pos is the start of the hit on the plus strand.
+func (P *PSSM) Scan(FR FastaRecord, n int) []PSSMHit {
+
+	w := P.Width()
+	hits := make([]PSSMHit, 0)
+	if w == 0 || len(FR.Seq) < w {
+		return hits
+	}
+
+	rc := FastaRecord{Seq: make([]byte, len(FR.Seq)), encoded: FR.encoded}
+	copy(rc.Seq, FR.Seq)
+	rc.ReverseComplement()
+
+	for pos := 0; pos+w <= len(FR.Seq); pos++ {
+		hits = append(hits, PSSMHit{Pos: pos, Strand: '+', Score: P.ScoreAt(FR, pos)})
+		hits = append(hits, PSSMHit{Pos: len(FR.Seq) - w - pos, Strand: '-', Score: P.ScoreAt(rc, pos)})
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if n > 0 && n < len(hits) {
+		hits = hits[:n]
+	}
+
+	return hits
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"io"
+	"sort"
+)
+
+// The distance between a query and one background record
+type DistanceHit struct {
+	QueryID  string
+	TargetID string
+	Distance float64
+	Compared int // the number of sites compared
+}
+
+// QueryDistances computes the distance from each query to every record of a
+// background alignment, which is streamed from r rather than loaded, on threads
+// goroutines. Only query-vs-background distances are computed, so this scales to
+// backgrounds far too big for an all-vs-all matrix. emit is called (from one
+// goroutine, in background file order) with the hits for every query against each
+// background record.
+func QueryDistances(queries []FastaRecord, r io.Reader, measure DistanceMeasure, threads int, emit func([]DistanceHit) error) error {
+
+	if measure < DistanceSNP || measure > DistanceJC69 {
+		return errUnknownDistance
+	}
+	for _, Q := range queries {
+		if len(Q.Seq) != len(queries[0].Seq) {
+			return errDifferentWidths
+		}
+	}
+
+	compare := func(target FastaRecord) ([]DistanceHit, error) {
+		hits := make([]DistanceHit, len(queries))
+		for i := range queries {
+			if len(target.Seq) != len(queries[i].Seq) {
+				return []DistanceHit{}, errDifferentWidths
+			}
+			compared, differences := pairDifferences(&queries[i], &target)
+			hits[i] = DistanceHit{
+				QueryID:  queries[i].ID,
+				TargetID: target.ID,
+				Distance: distanceFrom(compared, differences, measure),
+				Compared: compared,
+			}
+		}
+		return hits, nil
+	}
+
+	return ProcessOrdered(NewReader(r), threads, compare, emit)
+}
+
+// NearestNeighbours finds the k closest background records (streamed from r) to each
+// query (or all of them if k < 1), as QueryDistances, returning them keyed by query ID and sorted by distance
+// (ties in background file order). Records with no sites in common with a query
+// are never neighbours of it. A query ID that appears more than once gets the
+// neighbours of its last occurrence.
+func NearestNeighbours(queries []FastaRecord, r io.Reader, measure DistanceMeasure, k, threads int) (map[string][]DistanceHit, error) {
+
+	nearest := make([][]DistanceHit, len(queries))
+
+	err := QueryDistances(queries, r, measure, threads, func(hits []DistanceHit) error {
+		for i, H := range hits {
+			if H.Compared == 0 {
+				continue
+			}
+			list := nearest[i]
+			if k > 0 && len(list) == k && H.Distance >= list[k-1].Distance {
+				continue
+			}
+			pos := sort.Search(len(list), func(j int) bool { return list[j].Distance > H.Distance })
+			if k < 1 || len(list) < k {
+				list = append(list, DistanceHit{})
+			}
+			copy(list[pos+1:], list[pos:])
+			list[pos] = H
+			nearest[i] = list
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]DistanceHit, len(queries))
+	for i, Q := range queries {
+		if nearest[i] == nil {
+			nearest[i] = []DistanceHit{}
+		}
+		result[Q.ID] = nearest[i]
+	}
+	return result, nil
+}
@@ -0,0 +1,130 @@
+package main
+
+// The closest panel record to a query in one window of the alignment
+type WindowParent struct {
+	Start    int     // 0-based alignment column
+	End      int     // half-open
+	Parent   string  // the ID of the closest panel record, "" if no sites could be compared
+	Tied     bool    // other panel records were just as close
+	Sites    int     // columns where the query and Parent both have an unambiguous base
+	Identity float64 // the fraction of those columns where they have the same base
+}
+
+// The result of screening one query for recombination
+type RecombinationScreen struct {
+	QueryID  string
+	Windows  []WindowParent
+	Switches int  // how many times the closest parent changes from one window to the next
+	Flagged  bool // Switches > 0
+}
+
+// ScreenRecombination finds, for each query and each window of the given size
+// (moving step columns at a time), the panel record with the highest identity to
+// it, as a first-pass screen for recombinants and chimeras. Queries and panel must
+// be aligned to each other (all the same length), and may be encoded or not. Only
+// columns where both records have an unambiguous base are compared, and windows with
+// none are ignored. A query is flagged if its closest parent switches, which isn't
+// counted when the parents of two windows are tied with each other.
+func ScreenRecombination(queries, panel []FastaRecord, size, step int) ([]RecombinationScreen, error) {
+
+	if size < 1 || step < 1 {
+		return []RecombinationScreen{}, errBadWindow
+	}
+	if len(queries) == 0 {
+		return []RecombinationScreen{}, nil
+	}
+	w := len(queries[0].Seq)
+	for _, set := range [][]FastaRecord{queries, panel} {
+		for i := range set {
+			if len(set[i].Seq) != w {
+				return []RecombinationScreen{}, errDifferentWidths
+			}
+		}
+	}
+
+	type window struct{ start, end int }
+	windows := make([]window, 0, w/step+1)
+	for start := 0; start < w; start += step {
+		end := start + size
+		if end > w {
+			end = w
+		}
+		windows = append(windows, window{start, end})
+		if end == w {
+			break
+		}
+	}
+
+	// running totals of compared and identical columns, for one query/panel pair
+	compared := make([]int, w+1)
+	identical := make([]int, w+1)
+
+	screens := make([]RecombinationScreen, len(queries))
+	for q := range queries {
+		Q := &queries[q]
+		best := make([]WindowParent, len(windows))
+		// the indexes of the panel records tied for closest in each window
+		parents := make([][]int, len(windows))
+
+		for p := range panel {
+			P := &panel[p]
+			for i := 0; i < w; i++ {
+				a, b := Q.encodedAt(i), P.encodedAt(i)
+				compared[i+1], identical[i+1] = compared[i], identical[i]
+				if a&8 != 0 && b&8 != 0 {
+					compared[i+1]++
+					if a == b {
+						identical[i+1]++
+					}
+				}
+			}
+			for k, win := range windows {
+				sites := compared[win.end] - compared[win.start]
+				if sites == 0 {
+					continue
+				}
+				identity := float64(identical[win.end]-identical[win.start]) / float64(sites)
+				switch {
+				case parents[k] == nil || identity > best[k].Identity:
+					best[k] = WindowParent{Parent: P.ID, Sites: sites, Identity: identity}
+					parents[k] = []int{p}
+				case identity == best[k].Identity:
+					parents[k] = append(parents[k], p)
+				}
+			}
+		}
+
+		RS := RecombinationScreen{QueryID: Q.ID, Windows: best}
+		var last []int
+		for k, win := range windows {
+			best[k].Start, best[k].End = win.start, win.end
+			best[k].Tied = len(parents[k]) > 1
+			if parents[k] == nil {
+				continue
+			}
+			if last != nil && !sharesParent(last, parents[k]) {
+				RS.Switches++
+			}
+			last = parents[k]
+		}
+		RS.Flagged = RS.Switches > 0
+		screens[q] = RS
+	}
+
+	return screens, nil
+}
+
+// sharesParent reports whether two sorted lists of panel indexes have one in common
+func sharesParent(a, b []int) bool {
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			return true
+		case a[i] < b[j]:
+			i++
+		default:
+			j++
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package main
+
+// A RecoveredError is a malformed record that a Reader in recovery mode skipped
+type RecoveredError struct {
+	Record int // the number of records that Read had returned before this one
+	Err    error
+}
+
+// WithRecovery makes the Reader skip malformed records instead of stopping at the
+// first one: after an error it discards everything up to the next line that starts
+// with '>' and carries on from there. The errors are kept, and can be had from
+// RecoveredErrors. Invalid nucleotides are treated as malformed records when
+// encoding (see WithEncoding), rather than causing a panic. Errors from the
+// underlying reader and from transforms are still returned by Read.
+func WithRecovery() ReaderOption {
+	return func(r *Reader) {
+		r.recovering = true
+	}
+}
+
+// RecoveredErrors returns the errors for the records that have been skipped so far
+// in recovery mode
+func (r *Reader) RecoveredErrors() []RecoveredError {
+	return r.recovered
+}
+
+// recoverable reports whether err is from a malformed record, rather than from the
+// underlying reader
+func recoverable(err error) bool {
+	switch err {
+	case errBadlyFormedFasta, errInvalidUTF8Header, errNonASCIIHeader, errInvalidNucleotide:
+		return true
+	}
+	return false
+}
+
+// resync discards input up to the next header
+func (r *Reader) resync() error {
+	if r.block != nil {
+		return r.skipBlockSequence()
+	}
+	return r.skipSequence()
+}
+
+// encodeLine encodes a line of sequence in place. In recovery mode an invalid
+// nucleotide is an error, before any of the line is changed; otherwise it panics.
+func (r *Reader) encodeLine(line []byte) error {
+	if r.recovering {
+		for _, nuc := range line {
+			if encodingArray[nuc] == 0 {
+				return errInvalidNucleotide
+			}
+		}
+	}
+	encodeBytes(line)
+	return nil
+}
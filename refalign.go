@@ -0,0 +1,77 @@
+package main
+
+import "sync"
+
+// AlignToReference aligns every query to the reference (with AlignGlobal) and
+// stacks the results into a multiple alignment in reference coordinates: every
+// output record is the same length as the reference, with gaps where the query
+// has a deletion. Bases that the query has in addition to the reference are
+// removed from the alignment and returned as insertions instead.
+//
+// The output records are in the same order as queries and keep their encoding.
+// Alignments are run on up to threads goroutines at once. Each alignment is done in
+// linear space (see AlignGlobal), needing roughly 70 bytes per base of the query on
+// 64-bit platforms (about 2 MB for a 30 kb viral genome), so peak memory is about
+// threads times that. Time grows with the product of the reference and query
+// lengths.
+func AlignToReference(ref FastaRecord, queries []FastaRecord, params AlignParams, threads int) ([]FastaRecord, []Indel) {
+
+	if threads < 1 {
+		threads = 1
+	}
+
+	aligned := make([]FastaRecord, len(queries))
+	insertions := make([][]Indel, len(queries))
+
+	var wg sync.WaitGroup
+	jobs := make(chan int)
+
+	for t := 0; t < threads; t++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				aligned[i], insertions[i] = alignOneToReference(ref, queries[i], params)
+				aligned[i].Idx = i
+			}
+		}()
+	}
+
+	for i := range queries {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	allInsertions := make([]Indel, 0)
+	for _, ins := range insertions {
+		allInsertions = append(allInsertions, ins...)
+	}
+
+	return aligned, allInsertions
+}
+
+func alignOneToReference(ref, query FastaRecord, params AlignParams) (FastaRecord, []Indel) {
+
+	PA := AlignGlobal(ref, query, params)
+
+	insertions := make([]Indel, 0)
+	indels, _ := RecordIndels(PA.A, PA.B, NewCoordinateMap(PA.A))
+	for _, ID := range indels {
+		if ID.Type == DiffInsertion {
+			insertions = append(insertions, ID)
+		}
+	}
+
+	// drop the columns where the reference has a gap
+	seq := make([]byte, 0, len(ref.Seq))
+	for i := range PA.A.Seq {
+		if PA.A.encodedAt(i) != 244 {
+			seq = append(seq, PA.B.Seq[i])
+		}
+	}
+
+	FR := PA.B
+	FR.Seq = seq
+	return FR, insertions
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// One record's distance from, and differences to, a reference
+type ReferenceDistance struct {
+	ID          string
+	Compared    int          // the sites where both have an unambiguous base
+	SNPs        int          // the compared sites where the bases differ
+	Differences []Difference // as Diff(reference, record)
+	List        []string     // the differences in refposalt notation, as DifferenceList with the default options
+}
+
+// ReferenceDistances compares every record streamed from r (encoded or not) with
+// ref, on threads goroutines, without holding more than a few records in memory at
+// once. emit is called with each record's result as soon as it and every record
+// before it are done, so results come out in file order.
+func ReferenceDistances(ref FastaRecord, r io.Reader, threads int, emit func(ReferenceDistance) error) error {
+
+	compare := func(FR FastaRecord) (ReferenceDistance, error) {
+		diffs, err := Diff(ref, FR)
+		if err != nil {
+			return ReferenceDistance{}, err
+		}
+		compared, snps := pairDifferences(&ref, &FR)
+		list := differenceList(&ref, &FR, diffs, DifferenceListOptions{})
+		return ReferenceDistance{ID: FR.ID, Compared: compared, SNPs: snps, Differences: diffs, List: list}, nil
+	}
+
+	return ProcessOrdered(NewReader(r), threads, compare, emit)
+}
+
+// WriteReferenceDistances compares every record streamed from r with ref, as
+// ReferenceDistances, writing one tab-separated line per record to w as it goes: its
+// ID, the sites compared, the SNP distance, and its comma-separated difference list
+func WriteReferenceDistances(w io.Writer, ref FastaRecord, r io.Reader, threads int) error {
+
+	c := csv.NewWriter(w)
+	c.Comma = '\t'
+	if err := c.Write([]string{"id", "compared", "snps", "differences"}); err != nil {
+		return err
+	}
+
+	err := ReferenceDistances(ref, r, threads, func(RD ReferenceDistance) error {
+		return c.Write([]string{
+			RD.ID,
+			strconv.Itoa(RD.Compared),
+			strconv.Itoa(RD.SNPs),
+			strings.Join(RD.List, ","),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Flush()
+	return c.Error()
+}
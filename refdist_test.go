@@ -0,0 +1,23 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteReferenceDistances(t *testing.T) {
+	ref := FastaRecord{ID: "ref", Seq: []byte("ACGTACGTAC")}
+	in := ">a\nACGTACGTAA\n>b\nNNGT--GRAC\n>c\nACGTACGTAC\n"
+	var buf bytes.Buffer
+	if err := WriteReferenceDistances(&buf, ref, strings.NewReader(in), 2); err != nil {
+		t.Fatal(err)
+	}
+	want := "id\tcompared\tsnps\tdifferences\n" +
+		"a\t10\t1\tC10A\n" +
+		"b\t5\t0\tdel:5:2\n" +
+		"c\t10\t0\t\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
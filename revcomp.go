@@ -0,0 +1,92 @@
+package main
+
+// Lookup tables for complementing plain and encoded sequences
+var (
+	complementArray        = makeComplementArray()
+	encodedComplementArray = makeEncodedComplementArray()
+)
+
+func makeComplementArray() [256]byte {
+	var byteArray [256]byte
+
+	// anything without a complement (gaps, N, ?, and invalid bytes) maps to itself
+	for i := range byteArray {
+		byteArray[i] = byte(i)
+	}
+
+	pairs := []string{"AT", "CG", "RY", "KM", "BV", "DH", "at", "cg", "ry", "km", "bv", "dh"}
+	for _, p := range pairs {
+		byteArray[p[0]] = p[1]
+		byteArray[p[1]] = p[0]
+	}
+
+	return byteArray
+}
+
+// In the encoding, the top four bits are A, G, C and T, so complementing means
+// swapping the A and T bits, and the G and C bits
+func makeEncodedComplementArray() [256]byte {
+	var byteArray [256]byte
+	for i := range byteArray {
+		b := byte(i)
+		hi := (b&128)>>3 | (b&16)<<3 | (b&64)>>1 | (b&32)<<1
+		byteArray[i] = hi | b&15
+	}
+	return byteArray
+}
+
+// reverseComplementBytes reverse complements seq in place
+func reverseComplementBytes(seq []byte, encoded bool) {
+	table := &complementArray
+	if encoded {
+		table = &encodedComplementArray
+	}
+	for i, j := 0, len(seq)-1; i <= j; i, j = i+1, j-1 {
+		seq[i], seq[j] = table[seq[j]], table[seq[i]]
+	}
+}
+
+// ReverseComplement reverse complements a fasta record in place. It works on
+// encoded and plain records, and preserves case in plain ones.
+func (FR *FastaRecord) ReverseComplement() {
+	reverseComplementBytes(FR.Seq, FR.encoded)
+}
+
+// Complement complements a fasta record in place, without reversing it
+func (FR *FastaRecord) Complement() {
+	table := &complementArray
+	if FR.encoded {
+		table = &encodedComplementArray
+	}
+	for i, b := range FR.Seq {
+		FR.Seq[i] = table[b]
+	}
+}
+
+// Reverse reverses a fasta record in place, without complementing it
+func (FR *FastaRecord) Reverse() {
+	for i, j := 0, len(FR.Seq)-1; i < j; i, j = i+1, j-1 {
+		FR.Seq[i], FR.Seq[j] = FR.Seq[j], FR.Seq[i]
+	}
+}
+
+// ComplementCopy returns a complemented copy of a record, leaving it unchanged
+func (FR *FastaRecord) ComplementCopy() FastaRecord {
+	c := FR.Clone()
+	c.Complement()
+	return c
+}
+
+// ReverseCopy returns a reversed copy of a record, leaving it unchanged
+func (FR *FastaRecord) ReverseCopy() FastaRecord {
+	c := FR.Clone()
+	c.Reverse()
+	return c
+}
+
+// ReverseComplementCopy returns a reverse complemented copy of a record, leaving it unchanged
+func (FR *FastaRecord) ReverseComplementCopy() FastaRecord {
+	c := FR.Clone()
+	c.ReverseComplement()
+	return c
+}
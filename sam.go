@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The SAM flag bits used when extracting reads
+const (
+	samUnmapped      = 0x4
+	samReverse       = 0x10
+	samSecondary     = 0x100
+	samSupplementary = 0x800
+)
+
+// One alignment line of a SAM (or BAM) file. Pos is 1-based, as in SAM. Qual is
+// phred+33 ASCII, or nil if the file has no qualities for the read.
+type SAMRecord struct {
+	Name    string
+	Flag    int
+	RefName string
+	Pos     int
+	MapQ    int
+	Cigar   []CigarOp
+	Seq     []byte
+	Qual    []byte
+}
+
+var (
+	errBadlyFormedSAM = errors.New("Badly formed SAM line")
+	errBadlyFormedBAM = errors.New("Badly formed BAM file")
+)
+
+// A SAMReader reads the alignment lines of a SAM file, skipping the header
+type SAMReader struct {
+	r *bufio.Reader
+}
+
+func NewSAMReader(f io.Reader) *SAMReader {
+	return &SAMReader{r: bufio.NewReader(f)}
+}
+
+// Read returns the next alignment line, or io.EOF at the end of the file
+func (r *SAMReader) Read() (SAMRecord, error) {
+	for {
+		line, err := r.r.ReadString('\n')
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			return SAMRecord{}, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if len(line) == 0 || line[0] == '@' {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) < 11 {
+			return SAMRecord{}, errBadlyFormedSAM
+		}
+		SR := SAMRecord{Name: fields[0], RefName: fields[2]}
+		if SR.Flag, err = strconv.Atoi(fields[1]); err != nil {
+			return SAMRecord{}, errBadlyFormedSAM
+		}
+		if SR.Pos, err = strconv.Atoi(fields[3]); err != nil {
+			return SAMRecord{}, errBadlyFormedSAM
+		}
+		if SR.MapQ, err = strconv.Atoi(fields[4]); err != nil {
+			return SAMRecord{}, errBadlyFormedSAM
+		}
+		if fields[5] != "*" {
+			if SR.Cigar, err = ParseCigar(fields[5]); err != nil {
+				return SAMRecord{}, err
+			}
+		}
+		if fields[9] != "*" {
+			SR.Seq = []byte(fields[9])
+		}
+		if fields[10] != "*" {
+			SR.Qual = []byte(fields[10])
+			if len(SR.Qual) != len(SR.Seq) {
+				return SAMRecord{}, errBadlyFormedSAM
+			}
+		}
+		return SR, nil
+	}
+}
+
+// A BAMReader reads the alignment records of a BAM file
+type BAMReader struct {
+	r        *bufio.Reader
+	refNames []string
+}
+
+// NewBAMReader reads the BAM header from f, which should be the BAM file itself (not
+// decompressed). BGZF blocks are gzip members, which compress/gzip reads in sequence.
+func NewBAMReader(f io.Reader) (*BAMReader, error) {
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	r := &BAMReader{r: bufio.NewReader(gz)}
+
+	magic := make([]byte, 4)
+	if _, err = io.ReadFull(r.r, magic); err != nil || !bytes.Equal(magic, []byte("BAM\x01")) {
+		return nil, errBadlyFormedBAM
+	}
+
+	var lText, nRef int32
+	if err = binary.Read(r.r, binary.LittleEndian, &lText); err != nil || lText < 0 {
+		return nil, errBadlyFormedBAM
+	}
+	if _, err = r.r.Discard(int(lText)); err != nil {
+		return nil, errBadlyFormedBAM
+	}
+	if err = binary.Read(r.r, binary.LittleEndian, &nRef); err != nil || nRef < 0 {
+		return nil, errBadlyFormedBAM
+	}
+	r.refNames = make([]string, nRef)
+	for i := range r.refNames {
+		var lName, lRef int32
+		if err = binary.Read(r.r, binary.LittleEndian, &lName); err != nil || lName < 1 {
+			return nil, errBadlyFormedBAM
+		}
+		name := make([]byte, lName)
+		if _, err = io.ReadFull(r.r, name); err != nil {
+			return nil, errBadlyFormedBAM
+		}
+		r.refNames[i] = string(name[:lName-1])
+		if err = binary.Read(r.r, binary.LittleEndian, &lRef); err != nil {
+			return nil, errBadlyFormedBAM
+		}
+	}
+
+	return r, nil
+}
+
+// the 4-bit BAM sequence code
+const bamSeqCodes = "=ACMGRSVTWYHKDBN"
+
+// Read returns the next alignment record, or io.EOF at the end of the file
+func (r *BAMReader) Read() (SAMRecord, error) {
+
+	var blockSize int32
+	if err := binary.Read(r.r, binary.LittleEndian, &blockSize); err == io.EOF {
+		return SAMRecord{}, io.EOF
+	} else if err != nil || blockSize < 32 {
+		return SAMRecord{}, errBadlyFormedBAM
+	}
+	block := make([]byte, blockSize)
+	if _, err := io.ReadFull(r.r, block); err != nil {
+		return SAMRecord{}, errBadlyFormedBAM
+	}
+
+	le := binary.LittleEndian
+	refID := int32(le.Uint32(block[0:]))
+	SR := SAMRecord{
+		Pos:  int(int32(le.Uint32(block[4:]))) + 1,
+		MapQ: int(block[9]),
+		Flag: int(le.Uint16(block[14:])),
+	}
+	lName := int(block[8])
+	nCigar := int(le.Uint16(block[12:]))
+	lSeq := int(int32(le.Uint32(block[16:])))
+
+	if refID >= 0 && int(refID) < len(r.refNames) {
+		SR.RefName = r.refNames[refID]
+	} else {
+		SR.RefName = "*"
+	}
+
+	p := 32
+	if lName < 1 || lSeq < 0 || p+lName+4*nCigar+(lSeq+1)/2+lSeq > len(block) {
+		return SAMRecord{}, errBadlyFormedBAM
+	}
+	SR.Name = string(block[p : p+lName-1])
+	p += lName
+
+	SR.Cigar = make([]CigarOp, nCigar)
+	for i := range SR.Cigar {
+		c := le.Uint32(block[p:])
+		if c&15 > 8 {
+			return SAMRecord{}, errBadlyFormedBAM
+		}
+		SR.Cigar[i] = CigarOp{Op: "MIDNSHP=X"[c&15], Length: int(c >> 4)}
+		p += 4
+	}
+
+	if lSeq > 0 {
+		SR.Seq = make([]byte, lSeq)
+		for i := range SR.Seq {
+			b := block[p+i/2]
+			if i%2 == 0 {
+				b >>= 4
+			}
+			SR.Seq[i] = bamSeqCodes[b&15]
+		}
+		p += (lSeq + 1) / 2
+
+		if block[p] != 0xff {
+			SR.Qual = make([]byte, lSeq)
+			for i := range SR.Qual {
+				SR.Qual[i] = block[p+i] + 33
+			}
+		}
+	}
+
+	return SR, nil
+}
+
+// Options controlling which reads are extracted from a SAM or BAM file, and how
+type ReadExtractOptions struct {
+	SkipUnmapped        bool
+	SkipSecondary       bool
+	SkipSupplementary   bool
+	SkipHardClipped     bool // skip reads whose stored sequence is incomplete
+	TrimSoftClips       bool // drop soft-clipped bases from the ends of the read
+	OriginalOrientation bool // reverse complement reads that aligned to the minus strand
+}
+
+// Extract converts an alignment record into a FastqRecord according to opts. ok is
+// false if the read is filtered out, or has no stored sequence, or its qualities
+// don't match its sequence.
+func (SR SAMRecord) Extract(opts ReadExtractOptions) (FQ FastqRecord, ok bool) {
+
+	if len(SR.Seq) == 0 || SR.Qual != nil && len(SR.Qual) != len(SR.Seq) ||
+		opts.SkipUnmapped && SR.Flag&samUnmapped != 0 ||
+		opts.SkipSecondary && SR.Flag&samSecondary != 0 ||
+		opts.SkipSupplementary && SR.Flag&samSupplementary != 0 {
+		return FastqRecord{}, false
+	}
+
+	// soft clips can only be preceded (or followed) by hard clips
+	start, end := 0, len(SR.Seq)
+	for i, op := range SR.Cigar {
+		if op.Op == 'H' && opts.SkipHardClipped {
+			return FastqRecord{}, false
+		}
+		if op.Op != 'S' || !opts.TrimSoftClips {
+			continue
+		}
+		if i == 0 || i == 1 && SR.Cigar[0].Op == 'H' {
+			start = op.Length
+		} else {
+			end -= op.Length
+		}
+	}
+	if start > end {
+		return FastqRecord{}, false
+	}
+
+	FQ = FastqRecord{ID: SR.Name, Description: SR.Name}
+	FQ.Seq = make([]byte, end-start)
+	copy(FQ.Seq, SR.Seq[start:end])
+	if SR.Qual != nil {
+		FQ.Qual = make([]byte, end-start)
+		copy(FQ.Qual, SR.Qual[start:end])
+	} else {
+		FQ.Qual = bytes.Repeat([]byte{'I'}, end-start)
+	}
+
+	if opts.OriginalOrientation && SR.Flag&samReverse != 0 {
+		reverseComplementBytes(FQ.Seq, false)
+		for i, j := 0, len(FQ.Qual)-1; i < j; i, j = i+1, j-1 {
+			FQ.Qual[i], FQ.Qual[j] = FQ.Qual[j], FQ.Qual[i]
+		}
+	}
+
+	return FQ, true
+}
+
+// The interface shared by SAMReader and BAMReader
+type AlignmentReader interface {
+	Read() (SAMRecord, error)
+}
+
+// ExtractReads reads every record from an AlignmentReader and returns the reads
+// that pass opts. Reads with no stored qualities are given a quality of 'I'.
+func ExtractReads(r AlignmentReader, opts ReadExtractOptions) ([]FastqRecord, error) {
+	reads := make([]FastqRecord, 0)
+	for {
+		SR, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return []FastqRecord{}, err
+		}
+		if FQ, ok := SR.Extract(opts); ok {
+			FQ.Idx = len(reads)
+			reads = append(reads, FQ)
+		}
+	}
+	return reads, nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSAMQualLength(t *testing.T) {
+	r := NewSAMReader(strings.NewReader("@HD\tVN:1.6\nr1\t0\tref\t1\t60\t4M\t*\t0\t0\tACGT\tII\n"))
+	if _, err := r.Read(); err != errBadlyFormedSAM {
+		t.Errorf("got %v, want errBadlyFormedSAM", err)
+	}
+
+	r = NewSAMReader(strings.NewReader("r1\t0\tref\t1\t60\t4M\t*\t0\t0\tACGT\t*\n"))
+	SR, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if FQ, ok := SR.Extract(ReadExtractOptions{}); !ok || string(FQ.Qual) != "IIII" {
+		t.Errorf("got %q, %v", FQ.Qual, ok)
+	}
+
+	SR = SAMRecord{Name: "r2", Cigar: []CigarOp{{Op: 'S', Length: 2}, {Op: 'M', Length: 2}}, Seq: []byte("ACGT"), Qual: []byte("I")}
+	if _, ok := SR.Extract(ReadExtractOptions{TrimSoftClips: true}); ok {
+		t.Error("extracted a read with a short QUAL")
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// One line of an AGP (v2.1) file, describing either a contig ('W') or a gap ('N')
+// within a scaffold. Coordinates are 1-based and inclusive.
+type AGPLine struct {
+	Object       string
+	ObjectBeg    int
+	ObjectEnd    int
+	PartNumber   int
+	Type         byte // 'W' or 'N'
+	ComponentID  string
+	ComponentBeg int
+	ComponentEnd int
+	GapLength    int
+}
+
+// SplitScaffold breaks a record into contigs at every run of at least minGap Ns.
+// Contigs are named <ID>_ctg1, <ID>_ctg2, ... in order along the scaffold, and
+// the returned AGP lines describe how they (and the gaps between them) make up
+// the original record. Runs of Ns at the very start or end of the record are kept
+// in the first or last contig rather than being written as gaps, because AGP objects
+// may not begin or end with a gap and must be described from position 1 to the end.
+// The record may be encoded or not; the contigs are copies.
+func SplitScaffold(FR FastaRecord, minGap int) ([]FastaRecord, []AGPLine) {
+
+	if minGap < 1 {
+		minGap = 1
+	}
+
+	// the [start, end) coordinates of the stretches between long runs of Ns
+	type interval struct{ start, end int }
+	pieces := make([]interval, 0)
+
+	prev := 0
+	for i := 0; i < len(FR.Seq); {
+		if FR.encodedAt(i) != 240 {
+			i++
+			continue
+		}
+		j := i
+		for j < len(FR.Seq) && FR.encodedAt(j) == 240 {
+			j++
+		}
+		if j-i >= minGap && i > 0 && j < len(FR.Seq) {
+			pieces = append(pieces, interval{prev, i})
+			prev = j
+		}
+		i = j
+	}
+	if prev < len(FR.Seq) {
+		pieces = append(pieces, interval{prev, len(FR.Seq)})
+	}
+
+	contigs := make([]FastaRecord, 0, len(pieces))
+	agp := make([]AGPLine, 0, 2*len(pieces))
+
+	part := 0
+	for n, p := range pieces {
+
+		if n > 0 {
+			part++
+			agp = append(agp, AGPLine{
+				Object:     FR.ID,
+				ObjectBeg:  pieces[n-1].end + 1,
+				ObjectEnd:  p.start,
+				PartNumber: part,
+				Type:       'N',
+				GapLength:  p.start - pieces[n-1].end,
+			})
+		}
+
+		name := FR.ID + "_ctg" + strconv.Itoa(n+1)
+		seq := make([]byte, p.end-p.start)
+		copy(seq, FR.Seq[p.start:p.end])
+		contigs = append(contigs, FastaRecord{ID: name, Description: name, Seq: seq, encoded: FR.encoded})
+
+		part++
+		agp = append(agp, AGPLine{
+			Object:       FR.ID,
+			ObjectBeg:    p.start + 1,
+			ObjectEnd:    p.end,
+			PartNumber:   part,
+			Type:         'W',
+			ComponentID:  name,
+			ComponentBeg: 1,
+			ComponentEnd: p.end - p.start,
+		})
+	}
+
+	return contigs, agp
+}
+
+// BreakScaffolds calls SplitScaffold on every record and concatenates the results
+func BreakScaffolds(records []FastaRecord, minGap int) ([]FastaRecord, []AGPLine) {
+	contigs := make([]FastaRecord, 0)
+	agp := make([]AGPLine, 0)
+	for _, FR := range records {
+		c, a := SplitScaffold(FR, minGap)
+		contigs = append(contigs, c...)
+		agp = append(agp, a...)
+	}
+	return contigs, agp
+}
+
+// WriteAGP writes AGP lines to w in the tab-separated AGP v2.1 layout. Gaps are
+// written as scaffold gaps of known length.
+func WriteAGP(w io.Writer, lines []AGPLine) error {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString("##agp-version\t2.1\n"); err != nil {
+		return err
+	}
+	for _, l := range lines {
+		var err error
+		if l.Type == 'N' {
+			_, err = fmt.Fprintf(bw, "%s\t%d\t%d\t%d\tN\t%d\tscaffold\tyes\tunspecified\n",
+				l.Object, l.ObjectBeg, l.ObjectEnd, l.PartNumber, l.GapLength)
+		} else {
+			_, err = fmt.Fprintf(bw, "%s\t%d\t%d\t%d\tW\t%s\t%d\t%d\t+\n",
+				l.Object, l.ObjectBeg, l.ObjectEnd, l.PartNumber, l.ComponentID, l.ComponentBeg, l.ComponentEnd)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
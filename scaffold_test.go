@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestSplitScaffoldContiguousAGP(t *testing.T) {
+	for _, seq := range []string{"NNNNACGTNNNNNACNNN", "ACGTNNNNNAC", "NNNNN", "NNNNNACGT", "A"} {
+		FR := FastaRecord{ID: "s", Seq: []byte(seq)}
+		contigs, agp := SplitScaffold(FR, 3)
+
+		pos, total := 1, 0
+		for k, l := range agp {
+			if l.ObjectBeg != pos || l.ObjectEnd < l.ObjectBeg || l.PartNumber != k+1 {
+				t.Fatalf("%s: line %d is %+v, expected it to start at %d", seq, k, l, pos)
+			}
+			pos = l.ObjectEnd + 1
+		}
+		if pos != len(seq)+1 {
+			t.Errorf("%s: AGP ends at %d, want %d", seq, pos-1, len(seq))
+		}
+		if len(agp) == 0 || agp[0].Type != 'W' || agp[len(agp)-1].Type != 'W' {
+			t.Errorf("%s: AGP must start and end with a contig: %+v", seq, agp)
+		}
+		for _, c := range contigs {
+			total += len(c.Seq)
+		}
+		for _, l := range agp {
+			total += l.GapLength
+		}
+		if total != len(seq) {
+			t.Errorf("%s: contigs and gaps cover %d bases", seq, total)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import "sort"
+
+// A Sequence gives read-only access to a sequence whatever its storage, so that code
+// can work on plain text, encoded records, packed sequences and views of indexed
+// (for instance memory-mapped) files without copying them into a record first. At
+// returns the nucleotide at position i as a decoded fasta character.
+type Sequence interface {
+	Len() int
+	At(i int) byte
+	// Range calls fn on each position in turn until it returns false
+	Range(fn func(i int, b byte) bool)
+	// Bytes returns the decoded sequence. For a PlainSeq this is the underlying
+	// slice, which must not be modified; other implementations return a copy.
+	Bytes() []byte
+}
+
+// PlainSeq is a Sequence stored as fasta text
+type PlainSeq []byte
+
+func (PS PlainSeq) Len() int      { return len(PS) }
+func (PS PlainSeq) At(i int) byte { return PS[i] }
+func (PS PlainSeq) Bytes() []byte { return PS }
+func (PS PlainSeq) Range(fn func(i int, b byte) bool) {
+	for i, b := range PS {
+		if !fn(i, b) {
+			return
+		}
+	}
+}
+
+// EncodedSeq is a Sequence stored in the package's encoding
+type EncodedSeq []byte
+
+func (ES EncodedSeq) Len() int      { return len(ES) }
+func (ES EncodedSeq) At(i int) byte { return decodingArray[ES[i]] }
+func (ES EncodedSeq) Range(fn func(i int, b byte) bool) {
+	for i, e := range ES {
+		if !fn(i, decodingArray[e]) {
+			return
+		}
+	}
+}
+func (ES EncodedSeq) Bytes() []byte {
+	b := make([]byte, len(ES))
+	for i, e := range ES {
+		b[i] = decodingArray[e]
+	}
+	return b
+}
+
+// Sequence returns a view of the record's sequence (a PlainSeq or an EncodedSeq)
+// that shares its memory
+func (FR *FastaRecord) Sequence() Sequence {
+	if FR.encoded {
+		return EncodedSeq(FR.Seq)
+	}
+	return PlainSeq(FR.Seq)
+}
+
+// An encodedView gives the encoded value at each position of a Sequence. The views of
+// records are read straight from their slices, and anything else through At.
+type encodedView struct {
+	enc, plain []byte
+	s          Sequence
+	n          int
+}
+
+func viewOf(s Sequence) encodedView {
+	switch v := s.(type) {
+	case EncodedSeq:
+		return encodedView{enc: v, n: len(v)}
+	case PlainSeq:
+		return encodedView{plain: v, n: len(v)}
+	}
+	return encodedView{s: s, n: s.Len()}
+}
+
+func (v *encodedView) at(i int) byte {
+	switch {
+	case v.enc != nil:
+		return v.enc[i]
+	case v.plain != nil:
+		return encodingArray[v.plain[i]]
+	}
+	return encodingArray[v.s.At(i)]
+}
+
+// NewRecordFromSequence returns an unencoded record holding a copy of s, for the
+// functions that only work on records. The single-record and pairwise analyses
+// CalcSequenceStats, CalcSequenceComposition, PassSequence, DiffSequences,
+// SequenceDistance and SequenceIdentity work on a Sequence directly. The rest take
+// records: those that return new or modified records (DustMask, ApplyMutations,
+// TranslateAlignment and the like), whole-alignment analyses (Consensus,
+// CountMatrix, Distances and the rest), and those that use a record's ID or
+// description.
+func NewRecordFromSequence(id string, s Sequence) FastaRecord {
+	seq := make([]byte, s.Len())
+	s.Range(func(i int, b byte) bool {
+		seq[i] = b
+		return true
+	})
+	return FastaRecord{ID: id, Seq: seq}
+}
+
+// a run of a character that PackedSeq can't store in two bits
+type packedRun struct {
+	Interval
+	char byte
+}
+
+// PackedSeq is a Sequence stored in two bits per base, a quarter of the memory of
+// fasta text. Anything other than A, C, G and T (N runs, gaps and ambiguity codes)
+// is stored separately as runs, so it is compact for sequences where these are rare.
+// Lowercase is stored as uppercase.
+type PackedSeq struct {
+	bits  []byte
+	n     int
+	other []packedRun
+}
+
+// NewPackedSeq packs any Sequence
+func NewPackedSeq(s Sequence) *PackedSeq {
+	PS := &PackedSeq{bits: make([]byte, (s.Len()+3)/4), n: s.Len(), other: make([]packedRun, 0)}
+	s.Range(func(i int, b byte) bool {
+		b = toUpper(b)
+		if idx := baseIndex(encodingArray[b]); idx >= 0 {
+			PS.bits[i/4] |= byte(idx) << (2 * (i % 4))
+			return true
+		}
+		if k := len(PS.other) - 1; k >= 0 && PS.other[k].End == i && PS.other[k].char == b {
+			PS.other[k].End++
+		} else {
+			PS.other = append(PS.other, packedRun{Interval{i, i + 1}, b})
+		}
+		return true
+	})
+	return PS
+}
+
+func (PS *PackedSeq) Len() int { return PS.n }
+
+func (PS *PackedSeq) At(i int) byte {
+	if i < 0 || i >= PS.n {
+		panic("PackedSeq index out of range")
+	}
+	k := sort.Search(len(PS.other), func(k int) bool { return PS.other[k].End > i })
+	if k < len(PS.other) && PS.other[k].Start <= i {
+		return PS.other[k].char
+	}
+	return acgt[PS.bits[i/4]>>(2*(i%4))&3]
+}
+
+func (PS *PackedSeq) Range(fn func(i int, b byte) bool) {
+	k := 0
+	for i := 0; i < PS.n; i++ {
+		for k < len(PS.other) && PS.other[k].End <= i {
+			k++
+		}
+		b := acgt[PS.bits[i/4]>>(2*(i%4))&3]
+		if k < len(PS.other) && PS.other[k].Start <= i {
+			b = PS.other[k].char
+		}
+		if !fn(i, b) {
+			return
+		}
+	}
+}
+
+func (PS *PackedSeq) Bytes() []byte {
+	b := make([]byte, PS.n)
+	PS.Range(func(i int, c byte) bool {
+		b[i] = c
+		return true
+	})
+	return b
+}
+
+// FaiSeq is a Sequence that reads one record straight out of the bytes of a fasta
+// file, using its .fai index entry to skip the line breaks. data is typically a
+// memory-mapped file (see MapFile), so that only the parts of the sequence that are
+// used are ever read from disk.
+type FaiSeq struct {
+	data  []byte
+	entry FaiEntry
+}
+
+// NewFaiSeq returns a view of the record described by entry in data, which must
+// hold the whole fasta file that the index was made from
+func NewFaiSeq(data []byte, entry FaiEntry) (FaiSeq, error) {
+	if entry.Length < 0 || entry.Length > 0 && (entry.LineBases < 1 || entry.LineWidth < entry.LineBases) {
+		return FaiSeq{}, errBadlyFormedFai
+	}
+	FS := FaiSeq{data: data, entry: entry}
+	if entry.Offset < 0 || entry.Length > 0 && FS.offset(entry.Length-1) >= int64(len(data)) {
+		return FaiSeq{}, errSeqBounds
+	}
+	return FS, nil
+}
+
+func (FS FaiSeq) offset(i int) int64 {
+	E := FS.entry
+	return E.Offset + int64(i/E.LineBases)*int64(E.LineWidth) + int64(i%E.LineBases)
+}
+
+func (FS FaiSeq) Len() int      { return FS.entry.Length }
+func (FS FaiSeq) At(i int) byte { return FS.data[FS.offset(i)] }
+func (FS FaiSeq) Range(fn func(i int, b byte) bool) {
+	for i := 0; i < FS.entry.Length; i++ {
+		if !fn(i, FS.data[FS.offset(i)]) {
+			return
+		}
+	}
+}
+func (FS FaiSeq) Bytes() []byte {
+	b := make([]byte, FS.entry.Length)
+	for i := range b {
+		b[i] = FS.data[FS.offset(i)]
+	}
+	return b
+}
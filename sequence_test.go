@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSequenceAnalysesMatchRecords(t *testing.T) {
+	a := FastaRecord{ID: "a", Seq: []byte("ACGTNNRY--ACGTacgt")}
+	b := FastaRecord{ID: "b", Seq: []byte("ACTTNARC-AAC--acgA")}
+	encoded := a
+	encoded.Detach()
+	encoded.MustEncode()
+
+	for _, s := range []Sequence{a.Sequence(), encoded.Sequence(), NewPackedSeq(a.Sequence())} {
+		stats := CalcSequenceStats("a", s)
+		want := CalcStats(a)
+		if stats != want {
+			t.Errorf("%T: got stats %+v, want %+v", s, stats, want)
+		}
+
+		diffs, err := DiffSequences(s, NewPackedSeq(b.Sequence()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantDiffs, _ := Diff(a, b)
+		if !reflect.DeepEqual(diffs, wantDiffs) {
+			t.Errorf("%T: got differences %+v, want %+v", s, diffs, wantDiffs)
+		}
+
+		d, compared, err := SequenceDistance(s, b.Sequence(), DistanceSNP)
+		wantCompared, wantDifferences := pairDifferences(&a, &b)
+		if err != nil || compared != wantCompared || d != float64(wantDifferences) {
+			t.Errorf("%T: got distance %v over %d sites, want %d over %d", s, d, compared, wantDifferences, wantCompared)
+		}
+
+		if C := CalcSequenceComposition("a", s); C != CalcComposition(a) {
+			t.Errorf("%T: got composition %+v, want %+v", s, C, CalcComposition(a))
+		}
+
+		identity, compared, err := SequenceIdentity(s, b.Sequence())
+		wantIdentity, wantIdentityCompared, _ := Identity(a, b)
+		if err != nil || identity != wantIdentity || compared != wantIdentityCompared {
+			t.Errorf("%T: got identity %v over %d sites, want %v over %d", s, identity, compared, wantIdentity, wantIdentityCompared)
+		}
+
+		QF := DefaultQualityFilter
+		QF.MaxAmbiguous = 1
+		if QF.PassSequence(s) || QF.Pass(a) {
+			t.Errorf("%T: passed a filter with too many ambiguity codes", s)
+		}
+	}
+
+	if _, _, err := SequenceDistance(PlainSeq("AC"), PlainSeq("A"), DistanceSNP); err != errDifferentWidths {
+		t.Errorf("got %v, want errDifferentWidths", err)
+	}
+}
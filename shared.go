@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"sync"
+)
+
+var errDuplicateID = errors.New("Duplicate record ID")
+
+// an immutable view of a SharedAlignment's records
+type alignmentSnapshot struct {
+	records []FastaRecord
+	index   map[string]int
+}
+
+// A SharedAlignment holds an alignment that many goroutines can read at once, e.g.
+// in a server answering queries. Reads never block each other and never see a
+// half-finished change, because changes are copy-on-write: a writer builds a new
+// snapshot of the records and swaps it in, and readers carry on with the snapshot
+// they started with.
+//
+// Records returned by a SharedAlignment share their Seq with it, so they must be
+// treated as read-only (use Clone to get a copy to modify).
+type SharedAlignment struct {
+	mu      sync.RWMutex // guards snap, which is only ever replaced, never changed
+	writeMu sync.Mutex   // serialises writers
+	snap    *alignmentSnapshot
+}
+
+// NewSharedAlignment indexes records by ID, returning errDuplicateID if an ID appears
+// twice or errDifferentWidths if they are not all the same length. The
+// SharedAlignment takes ownership of records, which must not be changed afterwards.
+func NewSharedAlignment(records []FastaRecord) (*SharedAlignment, error) {
+	index := make(map[string]int, len(records))
+	for i, FR := range records {
+		if len(FR.Seq) != len(records[0].Seq) {
+			return nil, errDifferentWidths
+		}
+		if _, ok := index[FR.ID]; ok {
+			return nil, errDuplicateID
+		}
+		index[FR.ID] = i
+	}
+	return &SharedAlignment{snap: &alignmentSnapshot{records: records, index: index}}, nil
+}
+
+func (SA *SharedAlignment) snapshot() *alignmentSnapshot {
+	SA.mu.RLock()
+	defer SA.mu.RUnlock()
+	return SA.snap
+}
+
+// Get returns the record with the given ID
+func (SA *SharedAlignment) Get(id string) (FastaRecord, bool) {
+	snap := SA.snapshot()
+	i, ok := snap.index[id]
+	if !ok {
+		return FastaRecord{}, false
+	}
+	return snap.records[i], true
+}
+
+// Len returns the number of records
+func (SA *SharedAlignment) Len() int {
+	return len(SA.snapshot().records)
+}
+
+// Records returns the current records, in order. The slice is a snapshot: later
+// changes to the SharedAlignment don't affect it, and it must not be modified.
+func (SA *SharedAlignment) Records() []FastaRecord {
+	return SA.snapshot().records
+}
+
+// Range calls fn on each record in order, stopping early if fn returns false. It
+// sees one consistent snapshot even if the alignment changes part way through.
+func (SA *SharedAlignment) Range(fn func(FastaRecord) bool) {
+	for _, FR := range SA.snapshot().records {
+		if !fn(FR) {
+			return
+		}
+	}
+}
+
+// Set adds FR to the alignment, or replaces the record with the same ID. It returns
+// errDifferentWidths if FR is not the same width as the other records.
+func (SA *SharedAlignment) Set(FR FastaRecord) error {
+	SA.writeMu.Lock()
+	defer SA.writeMu.Unlock()
+
+	old := SA.snapshot()
+	if len(old.records) > 0 && len(FR.Seq) != len(old.records[0].Seq) {
+		return errDifferentWidths
+	}
+
+	records := make([]FastaRecord, len(old.records), len(old.records)+1)
+	copy(records, old.records)
+
+	index := old.index
+	if i, ok := old.index[FR.ID]; ok {
+		records[i] = FR
+	} else {
+		index = make(map[string]int, len(old.index)+1)
+		for id, i := range old.index {
+			index[id] = i
+		}
+		index[FR.ID] = len(records)
+		records = append(records, FR)
+	}
+
+	SA.swap(&alignmentSnapshot{records: records, index: index})
+	return nil
+}
+
+// Delete removes the record with the given ID, reporting whether there was one
+func (SA *SharedAlignment) Delete(id string) bool {
+	SA.writeMu.Lock()
+	defer SA.writeMu.Unlock()
+
+	old := SA.snapshot()
+	if _, ok := old.index[id]; !ok {
+		return false
+	}
+
+	records := make([]FastaRecord, 0, len(old.records)-1)
+	index := make(map[string]int, len(old.index)-1)
+	for _, FR := range old.records {
+		if FR.ID != id {
+			index[FR.ID] = len(records)
+			records = append(records, FR)
+		}
+	}
+
+	SA.swap(&alignmentSnapshot{records: records, index: index})
+	return true
+}
+
+func (SA *SharedAlignment) swap(snap *alignmentSnapshot) {
+	SA.mu.Lock()
+	SA.snap = snap
+	SA.mu.Unlock()
+}
@@ -0,0 +1,108 @@
+package main
+
+import "math/rand"
+
+// Shuffle randomly permutes a record's sequence in place, which keeps its base
+// composition. It works on encoded and plain records.
+func (FR *FastaRecord) Shuffle(seed int64) {
+	rng := rand.New(rand.NewSource(seed))
+	rng.Shuffle(len(FR.Seq), func(i, j int) { FR.Seq[i], FR.Seq[j] = FR.Seq[j], FR.Seq[i] })
+}
+
+// ShuffleDinucleotides shuffles a record's sequence in place while keeping the count
+// of every dinucleotide (and so also the base composition and the first and last
+// bases), as in Altschul and Erickson (1985). This is the usual null model for motif
+// enrichment. It works on encoded and plain records.
+//
+// The sequence is treated as a walk through a graph with an edge from each base to
+// the next. A random last exit from each base is chosen such that they form a tree
+// leading to the final base; the other exits are shuffled; and then a new walk is
+// made that takes each base's exits in their new order (Kandel et al. 1996).
+func (FR *FastaRecord) ShuffleDinucleotides(seed int64) {
+
+	n := len(FR.Seq)
+	if n < 3 {
+		return
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	seq := FR.Seq
+
+	var edges [256][]byte
+	for i := 0; i+1 < n; i++ {
+		edges[seq[i]] = append(edges[seq[i]], seq[i+1])
+	}
+	last := seq[n-1]
+
+	// choose last exits until every base that has exits leads to the final base
+	var lastExit [256]int
+	for {
+		for v := range edges {
+			if len(edges[v]) > 0 {
+				lastExit[v] = rng.Intn(len(edges[v]))
+			}
+		}
+		if lastExitsFormTree(&edges, &lastExit, last) {
+			break
+		}
+	}
+
+	for v := range edges {
+		e := edges[v]
+		if len(e) == 0 {
+			continue
+		}
+		k := len(e) - 1
+		e[lastExit[v]], e[k] = e[k], e[lastExit[v]]
+		if byte(v) != last {
+			rng.Shuffle(k, func(i, j int) { e[i], e[j] = e[j], e[i] })
+		} else {
+			// the final base has no last exit to keep, since the walk ends there
+			rng.Shuffle(len(e), func(i, j int) { e[i], e[j] = e[j], e[i] })
+		}
+	}
+
+	var used [256]int
+	out := make([]byte, 1, n)
+	out[0] = seq[0]
+	for v := seq[0]; len(out) < n; {
+		next := edges[v][used[v]]
+		used[v]++
+		out = append(out, next)
+		v = next
+	}
+
+	copy(FR.Seq, out)
+}
+
+// lastExitsFormTree reports whether following the chosen last exits from every base
+// with exits reaches the final base
+func lastExitsFormTree(edges *[256][]byte, lastExit *[256]int, last byte) bool {
+
+	var reaches [256]bool
+	reaches[last] = true
+
+	for v := range edges {
+		if len(edges[v]) == 0 {
+			continue
+		}
+		// follow last exits, marking the path, until reaching something already
+		// known to reach the final base, or going round a loop
+		var onPath [256]bool
+		path := make([]int, 0)
+		u := v
+		for !reaches[u] {
+			if onPath[u] {
+				return false
+			}
+			onPath[u] = true
+			path = append(path, u)
+			u = int(edges[u][lastExit[u]])
+		}
+		for _, p := range path {
+			reaches[p] = true
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"strconv"
+)
+
+var (
+	errBadComposition = errors.New("Base composition must be non-negative and not all zero")
+	errBadRate        = errors.New("Rates must be between 0 and 1")
+)
+
+// randomBase draws a base from the cumulative A, C, G, T weights
+func randomBase(rng *rand.Rand, cumulative *[4]float64) byte {
+	x := rng.Float64() * cumulative[3]
+	for i, c := range cumulative {
+		if x < c {
+			return acgt[i]
+		}
+	}
+	return acgt[3]
+}
+
+// RandomSequences generates n random (unencoded) sequences of the given length,
+// named seq1, seq2, ..., whose bases are drawn independently with the relative
+// weights of A, C, G and T in composition
+func RandomSequences(n, length int, composition [4]float64, seed int64) ([]FastaRecord, error) {
+
+	var cumulative [4]float64
+	total := 0.0
+	for i, w := range composition {
+		if w < 0 {
+			return []FastaRecord{}, errBadComposition
+		}
+		total += w
+		cumulative[i] = total
+	}
+	if total == 0 {
+		return []FastaRecord{}, errBadComposition
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	records := make([]FastaRecord, n)
+	for i := range records {
+		seq := make([]byte, length)
+		for j := range seq {
+			seq[j] = randomBase(rng, &cumulative)
+		}
+		id := "seq" + strconv.Itoa(i+1)
+		records[i] = FastaRecord{ID: id, Description: id, Seq: seq, Idx: i}
+	}
+
+	return records, nil
+}
+
+// Options for SimulateAlignment. Rates are per reference site, per record.
+type SimulateOptions struct {
+	SubstitutionRate float64
+	InsertionRate    float64
+	DeletionRate     float64
+	MaxIndelLength   int // indel lengths are uniform between 1 and this (1 if it is less than 1)
+	Seed             int64
+}
+
+// SimulateAlignment makes n descendants of a reference record (encoded or not) by
+// applying random substitutions, insertions and deletions to it, and returns them
+// aligned to each other, with the reference (gapped to fit) first. Substitutions
+// change an A, C, G or T to one of the other three, and inserted bases are uniformly
+// random. The simulated records are named sim1, sim2, ... and are unencoded.
+func SimulateAlignment(ref FastaRecord, n int, opts SimulateOptions) ([]FastaRecord, error) {
+
+	for _, r := range []float64{opts.SubstitutionRate, opts.InsertionRate, opts.DeletionRate} {
+		if r < 0 || r > 1 {
+			return []FastaRecord{}, errBadRate
+		}
+	}
+	maxIndel := opts.MaxIndelLength
+	if maxIndel < 1 {
+		maxIndel = 1
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	L := len(ref.Seq)
+
+	refSeq := make([]byte, L)
+	for i := range refSeq {
+		refSeq[i] = ref.residue(i)
+	}
+
+	// each descendant's base at every reference site, and the bases inserted after
+	// each site
+	sites := make([][]byte, n)
+	inserts := make([][][]byte, n)
+	widest := make([]int, L) // the longest insertion after each site
+
+	for r := 0; r < n; r++ {
+		seq := make([]byte, L)
+		copy(seq, refSeq)
+		ins := make([][]byte, L)
+
+		for i := 0; i < L; i++ {
+			// (sites that are already gaps, e.g. from an earlier deletion, are left alone)
+			if seq[i] != '-' {
+				if rng.Float64() < opts.DeletionRate {
+					end := i + 1 + rng.Intn(maxIndel)
+					for j := i; j < end && j < L; j++ {
+						seq[j] = '-'
+					}
+				} else if b := baseIndex(encodingArray[seq[i]]); b >= 0 && rng.Float64() < opts.SubstitutionRate {
+					seq[i] = acgt[(b+1+rng.Intn(3))%4]
+				}
+			}
+
+			if rng.Float64() < opts.InsertionRate {
+				ins[i] = make([]byte, 1+rng.Intn(maxIndel))
+				for j := range ins[i] {
+					ins[i][j] = acgt[rng.Intn(4)]
+				}
+				if len(ins[i]) > widest[i] {
+					widest[i] = len(ins[i])
+				}
+			}
+		}
+		sites[r], inserts[r] = seq, ins
+	}
+
+	w := L
+	for _, x := range widest {
+		w += x
+	}
+
+	// lay out a record's sites, padding every insertion out to the widest one
+	build := func(seq []byte, ins [][]byte) []byte {
+		out := make([]byte, 0, w)
+		for i := 0; i < L; i++ {
+			out = append(out, seq[i])
+			var inserted []byte
+			if ins != nil {
+				inserted = ins[i]
+			}
+			out = append(out, inserted...)
+			for j := len(inserted); j < widest[i]; j++ {
+				out = append(out, '-')
+			}
+		}
+		return out
+	}
+
+	records := make([]FastaRecord, 0, n+1)
+	records = append(records, FastaRecord{ID: ref.ID, Description: ref.Description, idSep: ref.idSep, Seq: build(refSeq, nil)})
+	for r := 0; r < n; r++ {
+		id := "sim" + strconv.Itoa(r+1)
+		records = append(records, FastaRecord{ID: id, Description: id, Seq: build(sites[r], inserts[r]), Idx: r + 1})
+	}
+
+	return records, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+// The GC skew of one window and the running total up to the end of it
+type SkewPoint struct {
+	Start      int // 0-based, half-open, as for WindowStats
+	End        int
+	Skew       float64 // (G - C) / (G + C) in the window
+	Cumulative float64 // the sum of Skew over this and every earlier window
+}
+
+// A GC skew profile along a sequence. In many bacterial chromosomes the cumulative
+// skew reaches its minimum near the origin of replication and its maximum near the
+// terminus.
+type GCSkewProfile struct {
+	Points []SkewPoint
+	MinPos int // the 0-based position (the End of the window) where the cumulative skew is lowest
+	Min    float64
+	MaxPos int // the 0-based position where the cumulative skew is highest
+	Max    float64
+}
+
+// GCSkew calculates the GC skew of windows along a record (as SlidingWindows) and
+// the cumulative skew, and finds the cumulative skew's minimum and maximum. For a
+// circular genome the skew is relative to the start of the sequence, which can be
+// moved (e.g. to the predicted origin) with Rotate.
+func GCSkew(FR FastaRecord, size, step int) (GCSkewProfile, error) {
+
+	windows, err := SlidingWindows(FR, size, step)
+	if err != nil {
+		return GCSkewProfile{}, err
+	}
+
+	profile := GCSkewProfile{Points: make([]SkewPoint, len(windows))}
+	cumulative := 0.0
+
+	for i, WS := range windows {
+		cumulative += WS.GCSkew
+		profile.Points[i] = SkewPoint{Start: WS.Start, End: WS.End, Skew: WS.GCSkew, Cumulative: cumulative}
+		if i == 0 || cumulative < profile.Min {
+			profile.Min, profile.MinPos = cumulative, WS.End
+		}
+		if i == 0 || cumulative > profile.Max {
+			profile.Max, profile.MaxPos = cumulative, WS.End
+		}
+	}
+
+	return profile, nil
+}
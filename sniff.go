@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+)
+
+var errUnknownFormat = errors.New("Input is neither fasta nor fastq")
+
+// A file format that Sniff can recognise
+type Format int
+
+const (
+	FormatFasta Format = iota
+	FormatFastq
+)
+
+func (F Format) String() string {
+	if F == FormatFastq {
+		return "fastq"
+	}
+	return "fasta"
+}
+
+// The interface shared by Reader and the fastq reader returned by NewAutoReader
+type RecordReader interface {
+	Read() (FastaRecord, error)
+}
+
+// Sniff works out whether f holds fasta or fastq from its first non-whitespace
+// byte, and returns a reader that reads the whole input from that byte on (the
+// leading whitespace is dropped). Empty input counts as fasta.
+func Sniff(f io.Reader) (Format, io.Reader, error) {
+	br := bufio.NewReader(f)
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			return FormatFasta, br, nil
+		} else if err != nil {
+			return FormatFasta, br, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '>':
+			br.UnreadByte()
+			return FormatFasta, br, nil
+		case '@':
+			br.UnreadByte()
+			return FormatFastq, br, nil
+		}
+		return FormatFasta, br, errUnknownFormat
+	}
+}
+
+// fastqRecordReader reads fastq as fasta records, dropping the qualities
+type fastqRecordReader struct {
+	r *FastqReader
+}
+
+func (r fastqRecordReader) Read() (FastaRecord, error) {
+	FQ, err := r.r.Read()
+	if err != nil {
+		return FastaRecord{}, err
+	}
+	return FQ.ToFasta(), nil
+}
+
+// NewAutoReader returns a RecordReader for f whether it holds fasta or fastq, so
+// that tools can take either (on stdin, for instance), gzipped or not. Qualities
+// are dropped from fastq; use Sniff and NewFastqReader to keep them. opts only apply
+// to fasta.
+func NewAutoReader(f io.Reader, opts ...ReaderOption) (RecordReader, Format, error) {
+	f, err := Decompress(f)
+	if err != nil {
+		return nil, FormatFasta, err
+	}
+	format, r, err := Sniff(f)
+	if err != nil {
+		return nil, format, err
+	}
+	if format == FormatFastq {
+		return fastqRecordReader{r: NewFastqReader(r)}, format, nil
+	}
+	return NewReader(r, opts...), format, nil
+}
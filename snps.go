@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Options for DifferenceList
+type DifferenceListOptions struct {
+	// Report sites where either record has an ambiguity code other than N. If false
+	// they are treated as missing data.
+	Ambiguities bool
+	// Report gaps before the record's first base or after its last (ignoring Ns) as
+	// deletions. If false they are treated as missing data, as they usually mean the
+	// sequencing didn't reach the ends of the reference.
+	TerminalGaps bool
+}
+
+// DifferenceList describes how FR differs from ref, a record of the same width
+// (either may be encoded or not), in refposalt notation: 1-based positions in the
+// ungapped reference, such as A123T for a substitution, del:22029:6 for six deleted
+// reference bases starting at 22029, and ins:100:3 for three bases inserted after
+// reference position 100. Ambiguous sites are written like substitutions (A123R).
+// Sites where either record has N or ? are never reported, and unless
+// opts.TerminalGaps is set deletions are clipped to the span of FR's sequence.
+func DifferenceList(ref, FR FastaRecord, opts DifferenceListOptions) ([]string, error) {
+
+	diffs, err := Diff(ref, FR)
+	if err != nil {
+		return []string{}, err
+	}
+	return differenceList(&ref, &FR, diffs, opts), nil
+}
+
+// differenceList formats diffs, which are Diff(ref, FR), as DifferenceList does
+func differenceList(ref, FR *FastaRecord, diffs []Difference, opts DifferenceListOptions) []string {
+
+	// the extent of FR's sequence, outside which gaps are missing data
+	first, last := -1, -1
+	for i := range FR.Seq {
+		if e := FR.encodedAt(i); e != 240 && e != 242 && e != 244 {
+			if first == -1 {
+				first = i
+			}
+			last = i
+		}
+	}
+
+	// refPos[i] is the number of reference bases in columns [0, i)
+	refPos := make([]int, len(ref.Seq)+1)
+	for i := range ref.Seq {
+		refPos[i+1] = refPos[i]
+		if ref.encodedAt(i) != 244 {
+			refPos[i+1]++
+		}
+	}
+
+	list := make([]string, 0, len(diffs))
+	for _, D := range diffs {
+		switch D.Type {
+		case DiffSubstitution:
+			list = append(list, D.A+strconv.Itoa(refPos[D.Start]+1)+D.B)
+		case DiffAmbiguous:
+			if opts.Ambiguities {
+				list = append(list, D.A+strconv.Itoa(refPos[D.Start]+1)+D.B)
+			}
+		case DiffDeletion:
+			start, end := D.Start, D.Start+D.Length
+			if !opts.TerminalGaps {
+				if start < first {
+					start = first
+				}
+				if end > last+1 {
+					end = last + 1
+				}
+				if first == -1 || end <= start {
+					continue
+				}
+			}
+			list = append(list, "del:"+strconv.Itoa(refPos[start]+1)+":"+strconv.Itoa(refPos[end]-refPos[start]))
+		case DiffInsertion:
+			list = append(list, "ins:"+strconv.Itoa(refPos[D.Start])+":"+strconv.Itoa(D.Length))
+		}
+	}
+
+	return list
+}
+
+// WriteDifferenceLists writes the DifferenceList of every record streamed from r
+// against ref as tab-separated lines of ID and comma-separated differences, in file
+// order, using threads goroutines
+func WriteDifferenceLists(w io.Writer, ref FastaRecord, r io.Reader, threads int, opts DifferenceListOptions) error {
+
+	c := csv.NewWriter(w)
+	c.Comma = '\t'
+	if err := c.Write([]string{"id", "differences"}); err != nil {
+		return err
+	}
+
+	list := func(FR FastaRecord) ([]string, error) {
+		diffs, err := DifferenceList(ref, FR, opts)
+		if err != nil {
+			return []string{}, err
+		}
+		return []string{FR.ID, strings.Join(diffs, ",")}, nil
+	}
+
+	if err := ProcessOrdered(NewReader(r), threads, list, c.Write); err != nil {
+		return err
+	}
+
+	c.Flush()
+	return c.Error()
+}
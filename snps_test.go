@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDifferenceListMissingData(t *testing.T) {
+	tests := []struct {
+		ref, seq string
+		terminal bool
+		want     string
+	}{
+		{"ACGTACGTAC", "AC--NNGTAC", false, "del:3:2"},
+		{"ACNNACGTAC", "AC----GTAC", false, "del:5:2"},
+		{"ACGNTACG", "AC----CG", false, "del:3:4"},
+		{"AC--GT", "ACNNGT", false, ""},
+		{"AC---GT", "ACNANGT", false, "ins:2:1"},
+		{"ACGTACGT", "--GTAC--", false, ""},
+		{"ACGTACGT", "--GTAC--", true, "del:1:2,del:7:2"},
+		{"ACGTACGT", "N-GTAC-?", false, ""},
+		{"ACGTACGT", "--------", false, ""},
+	}
+
+	for _, test := range tests {
+		ref := FastaRecord{ID: "ref", Seq: []byte(test.ref)}
+		FR := FastaRecord{ID: "a", Seq: []byte(test.seq)}
+		list, err := DifferenceList(ref, FR, DifferenceListOptions{TerminalGaps: test.terminal})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(list, ","); got != test.want {
+			t.Errorf("%s vs %s: got %q, want %q", test.seq, test.ref, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+var errBadlyFormedStates = errors.New("Badly formed state matrix")
+
+// the first bytes of a saved StateMatrix
+const stateMatrixMagic = "fastaigo-states-1\n"
+
+// The bits of a state set
+const (
+	StateA byte = 1
+	StateC byte = 2
+	StateG byte = 4
+	StateT byte = 8
+)
+
+// A StateMatrix holds an alignment as the set of nucleotide states each taxon could
+// have at each site, ready for a likelihood calculation: a bitmask of StateA, StateC,
+// StateG and StateT. Ambiguity codes have the bits of every base they stand for, and
+// gaps, N and '?' have all four (missing data). Weights gives the number of
+// alignment columns each site stands for, which is 1 unless the sites are compressed
+// patterns.
+type StateMatrix struct {
+	IDs     []string
+	Weights []int
+	States  [][]byte // States[taxon][site]
+}
+
+// stateSet returns the state set for an encoded base, or 0 if it isn't valid
+func stateSet(e byte) byte {
+	var s byte
+	for i, bit := range baseBits {
+		if e&bit != 0 {
+			s |= 1 << i
+		}
+	}
+	return s
+}
+
+// NewStateMatrix converts an alignment (encoded or not) to state sets, returning
+// errInvalidNucleotide if any record has a byte that isn't a nucleotide
+func NewStateMatrix(aln []FastaRecord) (StateMatrix, error) {
+	SM := StateMatrix{IDs: make([]string, len(aln)), States: make([][]byte, len(aln))}
+	w := 0
+	if len(aln) > 0 {
+		w = len(aln[0].Seq)
+	}
+	for i := range aln {
+		if len(aln[i].Seq) != w {
+			return StateMatrix{}, errDifferentWidths
+		}
+		SM.IDs[i] = aln[i].ID
+		SM.States[i] = make([]byte, w)
+		for j := range aln[i].Seq {
+			s := stateSet(aln[i].encodedAt(j))
+			if s == 0 {
+				return StateMatrix{}, errInvalidNucleotide
+			}
+			SM.States[i][j] = s
+		}
+	}
+	SM.Weights = make([]int, w)
+	for j := range SM.Weights {
+		SM.Weights[j] = 1
+	}
+	return SM, nil
+}
+
+// StateMatrixFromPatterns converts compressed site patterns to state sets, with the
+// patterns' weights
+func StateMatrixFromPatterns(SP SitePatterns) (StateMatrix, error) {
+	SM, err := NewStateMatrix(SP.Records)
+	if err != nil {
+		return StateMatrix{}, err
+	}
+	copy(SM.Weights, SP.Weights)
+	return SM, nil
+}
+
+// Sites returns the number of sites
+func (SM *StateMatrix) Sites() int {
+	return len(SM.Weights)
+}
+
+// Write saves the matrix in the following binary layout, where uvarints are as
+// written by encoding/binary:
+//
+//	"fastaigo-states-1\n"
+//	uvarint  the number of taxa, t
+//	uvarint  the number of sites, s
+//	t times: uvarint length, then that many bytes of taxon ID
+//	s times: uvarint site weight
+//	t*s bytes of state sets, taxon by taxon (all of the first taxon's sites first)
+func (SM *StateMatrix) Write(w io.Writer) error {
+
+	bw := bufio.NewWriter(w)
+	var buf [binary.MaxVarintLen64]byte
+
+	putUvarint := func(x uint64) {
+		n := binary.PutUvarint(buf[:], x)
+		bw.Write(buf[:n])
+	}
+
+	bw.WriteString(stateMatrixMagic)
+	putUvarint(uint64(len(SM.IDs)))
+	putUvarint(uint64(SM.Sites()))
+	for _, id := range SM.IDs {
+		putUvarint(uint64(len(id)))
+		bw.WriteString(id)
+	}
+	for _, weight := range SM.Weights {
+		putUvarint(uint64(weight))
+	}
+	for _, row := range SM.States {
+		bw.Write(row)
+	}
+
+	return bw.Flush()
+}
+
+// ReadStateMatrix loads a matrix saved by StateMatrix.Write
+func ReadStateMatrix(r io.Reader) (StateMatrix, error) {
+
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(stateMatrixMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != stateMatrixMagic {
+		return StateMatrix{}, errBadlyFormedStates
+	}
+
+	taxa, err := binary.ReadUvarint(br)
+	if err != nil {
+		return StateMatrix{}, errBadlyFormedStates
+	}
+	sites, err := binary.ReadUvarint(br)
+	if err != nil {
+		return StateMatrix{}, errBadlyFormedStates
+	}
+
+	SM := StateMatrix{IDs: make([]string, 0), Weights: make([]int, 0), States: make([][]byte, 0)}
+	for i := uint64(0); i < taxa; i++ {
+		id, _, err := readStoreBytes(br)
+		if err != nil {
+			return StateMatrix{}, errBadlyFormedStates
+		}
+		SM.IDs = append(SM.IDs, string(id))
+	}
+	for j := uint64(0); j < sites; j++ {
+		weight, err := binary.ReadUvarint(br)
+		if err != nil {
+			return StateMatrix{}, errBadlyFormedStates
+		}
+		SM.Weights = append(SM.Weights, int(weight))
+	}
+	for i := uint64(0); i < taxa; i++ {
+		row := make([]byte, sites)
+		if _, err = io.ReadFull(br, row); err != nil {
+			return StateMatrix{}, errBadlyFormedStates
+		}
+		SM.States = append(SM.States, row)
+	}
+
+	return SM, nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// Base composition of one sequence, tallied from the encoded representation
+// so that it is the same whether or not the record has been encoded
+type baseCounts struct {
+	A, C, G, T int
+	N          int // N and ?
+	Gap        int
+	Ambiguous  int // IUPAC ambiguity codes other than N
+	Other      int // bytes that are not in the encoding array
+}
+
+// the encoded value of the i-th position of a record, whether or not it is encoded
+func (FR *FastaRecord) encodedAt(i int) byte {
+	if FR.encoded {
+		return FR.Seq[i]
+	}
+	return encodingArray[FR.Seq[i]]
+}
+
+func countBases(FR *FastaRecord) baseCounts {
+	return countSequenceBases(FR.Sequence())
+}
+
+func countSequenceBases(s Sequence) baseCounts {
+	var bc baseCounts
+	v := viewOf(s)
+	for i := 0; i < v.n; i++ {
+		switch v.at(i) {
+		case 136:
+			bc.A++
+		case 40:
+			bc.C++
+		case 72:
+			bc.G++
+		case 24:
+			bc.T++
+		case 240, 242:
+			bc.N++
+		case 244:
+			bc.Gap++
+		case 0:
+			bc.Other++
+		default:
+			bc.Ambiguous++
+		}
+	}
+	return bc
+}
+
+// gcPercent is the percentage of unambiguous bases that are G or C. It is 0 if
+// there are no unambiguous bases.
+func (bc baseCounts) gcPercent() float64 {
+	acgt := bc.A + bc.C + bc.G + bc.T
+	if acgt == 0 {
+		return 0
+	}
+	return 100 * float64(bc.G+bc.C) / float64(acgt)
+}
+
+// Per-record statistics, as written by a StatsWriter
+type RecordStats struct {
+	ID        string  `json:"id"`
+	Length    int     `json:"length"`
+	GC        float64 `json:"gc_percent"` // GC% of the unambiguous bases
+	N         int     `json:"n_count"`    // includes '?'
+	Gaps      int     `json:"gap_count"`
+	Ambiguous int     `json:"ambiguous_count"` // IUPAC codes other than N
+	Score     int64   `json:"score"`
+}
+
+// CalcStats returns the statistics for one record, which may be encoded or not
+func CalcStats(FR FastaRecord) RecordStats {
+	bc := countBases(&FR)
+	return RecordStats{
+		ID:        FR.ID,
+		Length:    len(FR.Seq),
+		GC:        bc.gcPercent(),
+		N:         bc.N,
+		Gaps:      bc.Gap,
+		Ambiguous: bc.Ambiguous,
+		Score:     FR.Score,
+	}
+}
+
+// CalcSequenceStats is CalcStats for any Sequence, which has no Score
+func CalcSequenceStats(id string, s Sequence) RecordStats {
+	bc := countSequenceBases(s)
+	return RecordStats{
+		ID:        id,
+		Length:    s.Len(),
+		GC:        bc.gcPercent(),
+		N:         bc.N,
+		Gaps:      bc.Gap,
+		Ambiguous: bc.Ambiguous,
+	}
+}
+
+// The output formats that a StatsWriter can produce
+type StatsFormat int
+
+const (
+	StatsTSV StatsFormat = iota
+	StatsCSV
+	StatsJSON // one JSON object per line
+)
+
+var errUnknownStatsFormat = errors.New("Unknown stats format")
+
+var statsHeader = []string{"id", "length", "gc_percent", "n_count", "gap_count", "ambiguous_count", "score"}
+
+// A StatsWriter writes one line of statistics per record as they are passed to it,
+// so a report can be produced while records are still being read.
+type StatsWriter struct {
+	w       *bufio.Writer
+	c       *csv.Writer
+	j       *json.Encoder
+	started bool
+}
+
+func NewStatsWriter(w io.Writer, format StatsFormat) (*StatsWriter, error) {
+	SW := &StatsWriter{w: bufio.NewWriter(w)}
+	switch format {
+	case StatsTSV:
+		SW.c = csv.NewWriter(SW.w)
+		SW.c.Comma = '\t'
+	case StatsCSV:
+		SW.c = csv.NewWriter(SW.w)
+	case StatsJSON:
+		SW.j = json.NewEncoder(SW.w)
+	default:
+		return nil, errUnknownStatsFormat
+	}
+	return SW, nil
+}
+
+// Write calculates and writes the statistics for one record. The header line
+// (for TSV and CSV) is written before the first record.
+func (SW *StatsWriter) Write(FR FastaRecord) error {
+	return SW.WriteStats(CalcStats(FR))
+}
+
+// WriteStats writes statistics that have already been calculated
+func (SW *StatsWriter) WriteStats(RS RecordStats) error {
+	if SW.j != nil {
+		return SW.j.Encode(RS)
+	}
+	if !SW.started {
+		if err := SW.c.Write(statsHeader); err != nil {
+			return err
+		}
+		SW.started = true
+	}
+	return SW.c.Write([]string{
+		RS.ID,
+		strconv.Itoa(RS.Length),
+		strconv.FormatFloat(RS.GC, 'f', 2, 64),
+		strconv.Itoa(RS.N),
+		strconv.Itoa(RS.Gaps),
+		strconv.Itoa(RS.Ambiguous),
+		strconv.FormatInt(RS.Score, 10),
+	})
+}
+
+// Flush writes any buffered data to the underlying io.Writer
+func (SW *StatsWriter) Flush() error {
+	if SW.c != nil {
+		SW.c.Flush()
+		if err := SW.c.Error(); err != nil {
+			return err
+		}
+	}
+	return SW.w.Flush()
+}
+
+// WriteStatsReport reads every record from r and writes its statistics to w,
+// one record at a time, without holding the file in memory
+func WriteStatsReport(r io.Reader, w io.Writer, format StatsFormat) error {
+	SW, err := NewStatsWriter(w, format)
+	if err != nil {
+		return err
+	}
+	reader := NewReader(r)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if err = SW.Write(record); err != nil {
+			return err
+		}
+	}
+	return SW.Flush()
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+var errBadlyFormedStore = errors.New("Badly formed sequence store")
+
+// the first bytes of every store file
+const storeMagic = "fastaigo-store-1\n"
+
+// the bits of a stored record's flag byte
+const (
+	storeEncoded byte = 1
+	storeIDSep   byte = 2
+)
+
+// where one record lives in a store file
+type storeEntry struct {
+	offset int64 // the start of the record's ID
+	seq    int64 // the start of its sequence
+	length int   // the length of its sequence
+}
+
+// A SeqStore is an on-disk collection of sequences that can be looked up by ID, for
+// collections too big to hold in memory. Only the IDs and the file offsets of the
+// records are kept in memory; sequences are read from disk when they are asked for.
+//
+// The store is a single append-only file of records, so adding a record with an ID
+// that is already present supersedes the old one (which still takes up space). A
+// SeqStore is safe for concurrent use.
+type SeqStore struct {
+	mu    sync.Mutex
+	f     *os.File
+	w     *bufio.Writer
+	size  int64 // the size of the file, including anything still buffered in w
+	index map[string]storeEntry
+	order []string // the IDs, in the order they were first added
+}
+
+// CreateSeqStore creates a new, empty store at path, truncating any existing file
+func CreateSeqStore(path string) (*SeqStore, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	S := &SeqStore{f: f, w: bufio.NewWriter(f), index: make(map[string]storeEntry)}
+	if _, err = S.w.WriteString(storeMagic); err != nil {
+		f.Close()
+		return nil, err
+	}
+	S.size = int64(len(storeMagic))
+	return S, nil
+}
+
+// OpenSeqStore opens an existing store, reading its index of IDs. Sequences are
+// skipped over, so this is quick even for large stores.
+func OpenSeqStore(path string) (*SeqStore, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	S := &SeqStore{f: f, index: make(map[string]storeEntry)}
+	if err = S.loadIndex(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err = f.Seek(S.size, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	S.w = bufio.NewWriter(f)
+	return S, nil
+}
+
+// Each record is stored as the uvarint-prefixed ID and description, a flag byte
+// (storeEncoded if the sequence is encoded, plus storeIDSep if the description
+// excludes the ID, in which case the uvarint-prefixed text that separated them
+// follows), and the uvarint-prefixed sequence
+func (S *SeqStore) loadIndex() error {
+
+	r := bufio.NewReader(S.f)
+	magic := make([]byte, len(storeMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != storeMagic {
+		return errBadlyFormedStore
+	}
+	S.size = int64(len(storeMagic))
+
+	for {
+		offset := S.size
+		id, n, err := readStoreBytes(r)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return errBadlyFormedStore
+		}
+		S.size += int64(n)
+
+		// the description, which isn't needed for the index
+		_, n, err = readStoreBytes(r)
+		if err != nil {
+			return errBadlyFormedStore
+		}
+		S.size += int64(n)
+
+		flag, err := r.ReadByte()
+		if err != nil {
+			return errBadlyFormedStore
+		}
+		S.size++
+
+		if flag&storeIDSep != 0 {
+			_, n, err = readStoreBytes(r)
+			if err != nil {
+				return errBadlyFormedStore
+			}
+			S.size += int64(n)
+		}
+
+		length, err := binary.ReadUvarint(r)
+		if err != nil {
+			return errBadlyFormedStore
+		}
+		S.size += int64(uvarintLen(length))
+		if _, err = r.Discard(int(length)); err != nil {
+			return errBadlyFormedStore
+		}
+
+		S.add(string(id), storeEntry{offset: offset, seq: S.size, length: int(length)})
+		S.size += int64(length)
+	}
+}
+
+// readStoreBytes reads a uvarint-prefixed byte string, returning io.EOF only if
+// there was nothing left to read
+func readStoreBytes(r *bufio.Reader) ([]byte, int, error) {
+	l, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	b := make([]byte, l)
+	if _, err = io.ReadFull(r, b); err != nil {
+		return nil, 0, errBadlyFormedStore
+	}
+	return b, uvarintLen(l) + int(l), nil
+}
+
+func uvarintLen(x uint64) int {
+	var buf [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(buf[:], x)
+}
+
+func (S *SeqStore) add(id string, E storeEntry) {
+	if _, ok := S.index[id]; !ok {
+		S.order = append(S.order, id)
+	}
+	S.index[id] = E
+}
+
+// Put adds a record to the store. Its sequence is stored as it is, encoded or not.
+func (S *SeqStore) Put(FR FastaRecord) error {
+	S.mu.Lock()
+	defer S.mu.Unlock()
+	return S.put(FR)
+}
+
+func (S *SeqStore) put(FR FastaRecord) error {
+
+	var buf [binary.MaxVarintLen64]byte
+	offset := S.size
+
+	write := func(b []byte) error {
+		n := binary.PutUvarint(buf[:], uint64(len(b)))
+		if _, err := S.w.Write(buf[:n]); err != nil {
+			return err
+		}
+		_, err := S.w.Write(b)
+		S.size += int64(n + len(b))
+		return err
+	}
+
+	if err := write([]byte(FR.ID)); err != nil {
+		return err
+	}
+	if err := write([]byte(FR.Description)); err != nil {
+		return err
+	}
+	var flag byte
+	if FR.encoded {
+		flag |= storeEncoded
+	}
+	if FR.idSep != nil {
+		flag |= storeIDSep
+	}
+	if err := S.w.WriteByte(flag); err != nil {
+		return err
+	}
+	S.size++
+	if FR.idSep != nil {
+		if err := write([]byte(*FR.idSep)); err != nil {
+			return err
+		}
+	}
+
+	seq := S.size + int64(uvarintLen(uint64(len(FR.Seq))))
+	if err := write(FR.Seq); err != nil {
+		return err
+	}
+
+	S.add(FR.ID, storeEntry{offset: offset, seq: seq, length: len(FR.Seq)})
+	return nil
+}
+
+// Import adds every record in a fasta file to the store, encoding the sequences as
+// they are read (so it panics on invalid nucleotides, like MustEncode). It returns
+// the number of records added.
+func (S *SeqStore) Import(r io.Reader) (int, error) {
+	S.mu.Lock()
+	defer S.mu.Unlock()
+
+	reader := NewReader(r, WithEncoding())
+	n := 0
+	for {
+		FR, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return n, err
+		}
+		if err = S.put(FR); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, S.w.Flush()
+}
+
+// Get reads the record with the given ID from disk, returning errRecordNotFound if
+// there isn't one
+func (S *SeqStore) Get(id string) (FastaRecord, error) {
+	S.mu.Lock()
+	defer S.mu.Unlock()
+
+	E, ok := S.index[id]
+	if !ok {
+		return FastaRecord{}, errRecordNotFound
+	}
+	return S.read(E)
+}
+
+func (S *SeqStore) read(E storeEntry) (FastaRecord, error) {
+
+	if err := S.w.Flush(); err != nil {
+		return FastaRecord{}, err
+	}
+
+	r := bufio.NewReader(io.NewSectionReader(S.f, E.offset, E.seq-E.offset))
+	id, _, err := readStoreBytes(r)
+	if err != nil {
+		return FastaRecord{}, errBadlyFormedStore
+	}
+	description, _, err := readStoreBytes(r)
+	if err != nil {
+		return FastaRecord{}, errBadlyFormedStore
+	}
+	flag, err := r.ReadByte()
+	if err != nil {
+		return FastaRecord{}, errBadlyFormedStore
+	}
+
+	FR := FastaRecord{ID: string(id), Description: string(description), Seq: make([]byte, E.length), encoded: flag&storeEncoded != 0}
+	if flag&storeIDSep != 0 {
+		sep, _, err := readStoreBytes(r)
+		if err != nil {
+			return FastaRecord{}, errBadlyFormedStore
+		}
+		idSep := string(sep)
+		FR.idSep = &idSep
+	}
+	if _, err = S.f.ReadAt(FR.Seq, E.seq); err != nil {
+		return FastaRecord{}, err
+	}
+	return FR, nil
+}
+
+// Has reports whether there is a record with the given ID
+func (S *SeqStore) Has(id string) bool {
+	S.mu.Lock()
+	defer S.mu.Unlock()
+	_, ok := S.index[id]
+	return ok
+}
+
+// Len returns the number of records in the store
+func (S *SeqStore) Len() int {
+	S.mu.Lock()
+	defer S.mu.Unlock()
+	return len(S.order)
+}
+
+// IDs returns the IDs of the records, in the order they were first added
+func (S *SeqStore) IDs() []string {
+	S.mu.Lock()
+	defer S.mu.Unlock()
+	ids := make([]string, len(S.order))
+	copy(ids, S.order)
+	return ids
+}
+
+// Iterate reads each record from disk in turn and calls fn on it, in the order they
+// were first added, stopping at the first error
+func (S *SeqStore) Iterate(fn func(FastaRecord) error) error {
+	for _, id := range S.IDs() {
+		FR, err := S.Get(id)
+		if err != nil {
+			return err
+		}
+		if err = fn(FR); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close flushes anything buffered and closes the store's file
+func (S *SeqStore) Close() error {
+	S.mu.Lock()
+	defer S.mu.Unlock()
+	if err := S.w.Flush(); err != nil {
+		S.f.Close()
+		return err
+	}
+	return S.f.Close()
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"io"
+)
+
+// Stream reads the alignment in r like StreamAlignment, encoding each record and
+// checking that they are all the same width, and calls fn on each record in order.
+// Reading happens in its own goroutine, so the next record is parsed while fn runs.
+//
+// Stream returns the first error from reading or from fn, or ctx.Err() if ctx is
+// cancelled first. In every case the reading goroutine has exited by the time Stream
+// returns.
+func Stream(ctx context.Context, r io.Reader, fn func(FastaRecord) error) error {
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	records := make(chan FastaRecord)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(records)
+
+		reader := NewReader(r, WithEncoding())
+		first := true
+		var w int
+
+		for i := 0; ; i++ {
+			record, err := reader.Read()
+			if err == io.EOF {
+				return
+			} else if err != nil {
+				errs <- err
+				return
+			}
+
+			if first {
+				w = len(record.Seq)
+				first = false
+			} else if len(record.Seq) != w {
+				errs <- errDifferentWidths
+				return
+			}
+
+			record.Idx = i
+
+			select {
+			case records <- record:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case record, ok := <-records:
+			if !ok {
+				// the reader has finished, with or without an error
+				select {
+				case err := <-errs:
+					return err
+				default:
+					return nil
+				}
+			}
+			if err := fn(record); err != nil {
+				cancel()
+				// wait for the reader to notice
+				for range records {
+				}
+				return err
+			}
+		case <-ctx.Done():
+			for range records {
+			}
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"index/suffixarray"
+	"io"
+	"sort"
+)
+
+var errBadlyFormedIndex = errors.New("Badly formed suffix index")
+
+// the first bytes of a saved SuffixIndex
+const suffixIndexMagic = "fastaigo-sa-1\n"
+
+// A SuffixIndex is a suffix array over the sequences of one or more records, for
+// finding every exact occurrence of a substring quickly, e.g. when searching a
+// reference genome many times. Sequences are indexed in upper case, and ambiguity
+// codes only match themselves.
+type SuffixIndex struct {
+	ids    []string
+	starts []int // the offset of each record in the indexed text
+	sa     *suffixarray.Index
+}
+
+// An exact match found by a SuffixIndex, at a 0-based position in the record
+type SubstringHit struct {
+	RecordID string
+	Pos      int
+}
+
+// NewSuffixIndex builds a SuffixIndex over records, which may be encoded or not
+func NewSuffixIndex(records []FastaRecord) *SuffixIndex {
+
+	SI := &SuffixIndex{ids: make([]string, len(records)), starts: make([]int, len(records))}
+
+	// the records are joined with a 0 byte between them, so that no match can
+	// span two of them
+	size := 0
+	for _, FR := range records {
+		size += len(FR.Seq) + 1
+	}
+	text := make([]byte, 0, size)
+	for i := range records {
+		SI.ids[i] = records[i].ID
+		SI.starts[i] = len(text)
+		for j := range records[i].Seq {
+			text = append(text, records[i].residue(j))
+		}
+		text = append(text, 0)
+	}
+
+	SI.sa = suffixarray.New(text)
+	return SI
+}
+
+// Locate returns every occurrence of query (case-insensitive), sorted by record and
+// then position
+func (SI *SuffixIndex) Locate(query []byte) []SubstringHit {
+
+	q := upperBytes(query)
+	if len(q) == 0 {
+		return []SubstringHit{}
+	}
+
+	offsets := SI.sa.Lookup(q, -1)
+	sort.Ints(offsets)
+
+	hits := make([]SubstringHit, len(offsets))
+	for i, o := range offsets {
+		// the last record starting at or before o
+		r := sort.SearchInts(SI.starts, o+1) - 1
+		hits[i] = SubstringHit{RecordID: SI.ids[r], Pos: o - SI.starts[r]}
+	}
+	return hits
+}
+
+// Count returns the number of occurrences of query (case-insensitive)
+func (SI *SuffixIndex) Count(query []byte) int {
+	q := upperBytes(query)
+	if len(q) == 0 {
+		return 0
+	}
+	return len(SI.sa.Lookup(q, -1))
+}
+
+func upperBytes(b []byte) []byte {
+	u := make([]byte, len(b))
+	for i := range b {
+		u[i] = toUpper(b[i])
+	}
+	return u
+}
+
+// Write saves the index to w, so that it can be loaded again with ReadSuffixIndex
+// instead of being rebuilt
+func (SI *SuffixIndex) Write(w io.Writer) error {
+
+	bw := bufio.NewWriter(w)
+	var buf [binary.MaxVarintLen64]byte
+
+	putUvarint := func(x uint64) {
+		n := binary.PutUvarint(buf[:], x)
+		bw.Write(buf[:n])
+	}
+
+	bw.WriteString(suffixIndexMagic)
+	putUvarint(uint64(len(SI.ids)))
+	for i, id := range SI.ids {
+		putUvarint(uint64(len(id)))
+		bw.WriteString(id)
+		putUvarint(uint64(SI.starts[i]))
+	}
+	if err := SI.sa.Write(bw); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// ReadSuffixIndex loads an index saved by SuffixIndex.Write
+func ReadSuffixIndex(r io.Reader) (*SuffixIndex, error) {
+
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(suffixIndexMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != suffixIndexMagic {
+		return nil, errBadlyFormedIndex
+	}
+
+	n, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, errBadlyFormedIndex
+	}
+
+	SI := &SuffixIndex{ids: make([]string, 0, n), starts: make([]int, 0, n)}
+	for i := uint64(0); i < n; i++ {
+		id, _, err := readStoreBytes(br)
+		if err != nil {
+			return nil, errBadlyFormedIndex
+		}
+		start, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, errBadlyFormedIndex
+		}
+		SI.ids = append(SI.ids, string(id))
+		SI.starts = append(SI.starts, int(start))
+	}
+
+	SI.sa = new(suffixarray.Index)
+	if err = SI.sa.Read(br); err != nil {
+		return nil, errBadlyFormedIndex
+	}
+
+	return SI, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import "errors"
+
+// A GeneticCode gives the amino acid for each of the 64 codons, in the NCBI order
+// (TTT, TTC, TTA, TTG, TCT, ... with bases ordered T, C, A, G)
+type GeneticCode [64]byte
+
+var errUnknownGeneticCode = errors.New("Unknown genetic code table")
+
+// the NCBI translation tables that are supported, by table number
+var geneticCodes = map[int]string{
+	1:  "FFLLSSSSYY**CC*WLLLLPPPPHHQQRRRRIIIMTTTTNNKKSSRRVVVVAAAADDEEGGGG",
+	2:  "FFLLSSSSYY**CCWWLLLLPPPPHHQQRRRRIIMMTTTTNNKKSS**VVVVAAAADDEEGGGG",
+	3:  "FFLLSSSSYY**CCWWTTTTPPPPHHQQRRRRIIMMTTTTNNKKSSRRVVVVAAAADDEEGGGG",
+	4:  "FFLLSSSSYY**CCWWLLLLPPPPHHQQRRRRIIIMTTTTNNKKSSRRVVVVAAAADDEEGGGG",
+	5:  "FFLLSSSSYY**CCWWLLLLPPPPHHQQRRRRIIMMTTTTNNKKSSSSVVVVAAAADDEEGGGG",
+	11: "FFLLSSSSYY**CC*WLLLLPPPPHHQQRRRRIIIMTTTTNNKKSSRRVVVVAAAADDEEGGGG",
+}
+
+// StandardCode is NCBI translation table 1
+var StandardCode, _ = GetGeneticCode(1)
+
+// GetGeneticCode returns the NCBI translation table with the given number
+func GetGeneticCode(table int) (GeneticCode, error) {
+	var GC GeneticCode
+	aas, ok := geneticCodes[table]
+	if !ok {
+		return GC, errUnknownGeneticCode
+	}
+	copy(GC[:], aas)
+	return GC, nil
+}
+
+// the bits of the encoding for T, C, A and G, in NCBI table order
+var codonBits = [4]byte{16, 32, 128, 64}
+
+// TranslateCodon translates three encoded bases. A codon of three gaps translates to
+// '-'. Ambiguous codons translate to the amino acid that every possible resolution
+// would give, or 'X' if they disagree (or if the codon includes a partial gap or an
+// invalid byte).
+func (GC *GeneticCode) TranslateCodon(a, b, c byte) byte {
+	if a == 244 && b == 244 && c == 244 {
+		return '-'
+	}
+	if a == 244 || b == 244 || c == 244 || a == 0 || b == 0 || c == 0 {
+		return 'X'
+	}
+	var aa byte
+	for i, bi := range codonBits {
+		if a&bi == 0 {
+			continue
+		}
+		for j, bj := range codonBits {
+			if b&bj == 0 {
+				continue
+			}
+			for k, bk := range codonBits {
+				if c&bk == 0 {
+					continue
+				}
+				x := GC[i*16+j*4+k]
+				if aa != 0 && x != aa {
+					return 'X'
+				}
+				aa = x
+			}
+		}
+	}
+	return aa
+}
+
+// Translate translates a record (encoded or not) from the first base, ignoring any
+// incomplete codon at the end
+func (GC *GeneticCode) Translate(FR FastaRecord) []byte {
+	protein := make([]byte, 0, len(FR.Seq)/3)
+	for i := 0; i+2 < len(FR.Seq); i += 3 {
+		protein = append(protein, GC.TranslateCodon(FR.encodedAt(i), FR.encodedAt(i+1), FR.encodedAt(i+2)))
+	}
+	return protein
+}
+
+var errBadFrame = errors.New("Frame must be 0, 1 or 2")
+
+// A codon that a gap only partly covers, which puts the rest of the sequence out of
+// frame unless it is made up for nearby
+type FrameBreak struct {
+	RecordID string
+	Codon    int // the 0-based index of the codon in the translation
+	Pos      int // the 0-based alignment column that the codon starts at
+}
+
+// TranslateAlignment translates a codon-aligned nucleotide alignment (encoded or not)
+// into a protein alignment with the given NCBI translation table. Translation starts
+// at the 0-based offset frame, and any incomplete codon at the end is ignored. Codons
+// that are all gap become a single gap in the protein alignment; codons with only one
+// or two gaps become 'X' and are reported as FrameBreaks.
+func TranslateAlignment(aln []FastaRecord, frame int, table int) ([]FastaRecord, []FrameBreak, error) {
+
+	if frame < 0 || frame > 2 {
+		return []FastaRecord{}, []FrameBreak{}, errBadFrame
+	}
+	GC, err := GetGeneticCode(table)
+	if err != nil {
+		return []FastaRecord{}, []FrameBreak{}, err
+	}
+
+	proteins := make([]FastaRecord, len(aln))
+	breaks := make([]FrameBreak, 0)
+
+	for i := range aln {
+		FR := &aln[i]
+		if len(FR.Seq) != len(aln[0].Seq) {
+			return []FastaRecord{}, []FrameBreak{}, errDifferentWidths
+		}
+
+		protein := make([]byte, 0, len(FR.Seq)/3)
+		for j := frame; j+2 < len(FR.Seq); j += 3 {
+			a, b, c := FR.encodedAt(j), FR.encodedAt(j+1), FR.encodedAt(j+2)
+			gaps := 0
+			for _, e := range [3]byte{a, b, c} {
+				if e == 244 {
+					gaps++
+				}
+			}
+			if gaps == 1 || gaps == 2 {
+				breaks = append(breaks, FrameBreak{RecordID: FR.ID, Codon: len(protein), Pos: j})
+			}
+			protein = append(protein, GC.TranslateCodon(a, b, c))
+		}
+
+		proteins[i] = FastaRecord{ID: FR.ID, Description: FR.Description, idSep: FR.idSep, Seq: protein, Idx: FR.Idx}
+	}
+
+	return proteins, breaks, nil
+}
@@ -0,0 +1,143 @@
+package main
+
+// the side of the square tiles that Transpose copies one at a time, so that the reads
+// and the writes both stay in cache
+const transposeBlock = 64
+
+// the number of columns a ColumnIterator transposes at once
+const columnChunk = 1024
+
+// alignmentWidth returns the common length of the records, or errDifferentWidths
+func alignmentWidth(aln []FastaRecord) (int, error) {
+	if len(aln) == 0 {
+		return 0, nil
+	}
+	w := len(aln[0].Seq)
+	for i := range aln {
+		if len(aln[i].Seq) != w {
+			return 0, errDifferentWidths
+		}
+	}
+	return w, nil
+}
+
+// transposeInto copies columns [start, start+len(cols)) of the alignment into cols,
+// tile by tile. Every column is in the encoding of the first record.
+func transposeInto(aln []FastaRecord, start int, cols [][]byte) {
+	encoded := aln[0].encoded
+	for i0 := 0; i0 < len(aln); i0 += transposeBlock {
+		i1 := i0 + transposeBlock
+		if i1 > len(aln) {
+			i1 = len(aln)
+		}
+		for j0 := 0; j0 < len(cols); j0 += transposeBlock {
+			j1 := j0 + transposeBlock
+			if j1 > len(cols) {
+				j1 = len(cols)
+			}
+			for i := i0; i < i1; i++ {
+				seq := aln[i].Seq[start+j0 : start+j1]
+				switch {
+				case aln[i].encoded == encoded:
+					for j, b := range seq {
+						cols[j0+j][i] = b
+					}
+				case encoded:
+					for j, b := range seq {
+						cols[j0+j][i] = encodingArray[b]
+					}
+				default:
+					for j, b := range seq {
+						cols[j0+j][i] = decodingArray[b]
+					}
+				}
+			}
+		}
+	}
+}
+
+// Transpose turns an alignment of records × sites into one of sites × records: the
+// i-th byte of the j-th column is the j-th character of the i-th record. The columns
+// are encoded if the first record is, and not otherwise.
+func Transpose(aln []FastaRecord) ([][]byte, error) {
+	w, err := alignmentWidth(aln)
+	if err != nil {
+		return [][]byte{}, err
+	}
+	cols := make([][]byte, w)
+	backing := make([]byte, w*len(aln))
+	for j := range cols {
+		cols[j] = backing[j*len(aln) : (j+1)*len(aln) : (j+1)*len(aln)]
+	}
+	if len(aln) > 0 {
+		transposeInto(aln, 0, cols)
+	}
+	return cols, nil
+}
+
+// A ColumnIterator steps through the columns of an alignment, transposing them a
+// chunk at a time rather than all at once. Use it like a bufio.Scanner:
+//
+//	it, err := Alignment(records).Columns()
+//	...
+//	for it.Next() {
+//		col := it.Column()
+//		...
+//	}
+type ColumnIterator struct {
+	aln   []FastaRecord
+	width int
+	chunk [][]byte
+	start int // the position of chunk[0] in the alignment
+	pos   int
+}
+
+// Columns returns an iterator over the alignment's columns, which must all be the
+// same length
+func (A Alignment) Columns() (*ColumnIterator, error) {
+	w, err := alignmentWidth(A)
+	if err != nil {
+		return nil, err
+	}
+	n := columnChunk
+	if n > w {
+		n = w
+	}
+	CI := &ColumnIterator{aln: A, width: w, chunk: make([][]byte, n), pos: -1}
+	backing := make([]byte, n*len(A))
+	for j := range CI.chunk {
+		CI.chunk[j] = backing[j*len(A) : (j+1)*len(A) : (j+1)*len(A)]
+	}
+	return CI, nil
+}
+
+// Next advances to the next column, returning false when there are none left
+func (CI *ColumnIterator) Next() bool {
+	if CI.pos+1 >= CI.width {
+		CI.pos = CI.width
+		return false
+	}
+	CI.pos++
+	if CI.pos == 0 || CI.pos-CI.start == len(CI.chunk) {
+		CI.start = CI.pos
+		n := len(CI.chunk)
+		if n > CI.width-CI.start {
+			n = CI.width - CI.start
+		}
+		if len(CI.aln) > 0 {
+			transposeInto(CI.aln, CI.start, CI.chunk[:n])
+		}
+	}
+	return true
+}
+
+// Pos returns the 0-based position of the current column
+func (CI *ColumnIterator) Pos() int {
+	return CI.pos
+}
+
+// Column returns the current column, one byte per record, encoded if the first
+// record is. The slice is only valid until the next call to Next.
+func (CI *ColumnIterator) Column() []byte {
+	return CI.chunk[CI.pos-CI.start]
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"math"
+	"strings"
+)
+
+var errBadDistances = errors.New("Distance matrix must be square, non-empty and finite")
+
+// A node of a phylogenetic tree. Leaves have a Name and no Children.
+type TreeNode struct {
+	Name     string
+	Length   float64 // the length of the branch to the parent
+	Children []*TreeNode
+}
+
+func checkDistances(DM *DistanceMatrix) error {
+	n := len(DM.IDs)
+	if n == 0 || len(DM.D) != n {
+		return errBadDistances
+	}
+	for _, row := range DM.D {
+		if len(row) != n {
+			return errBadDistances
+		}
+		for _, d := range row {
+			if math.IsNaN(d) || math.IsInf(d, 0) {
+				return errBadDistances
+			}
+		}
+	}
+	return nil
+}
+
+// copyDistances returns a working copy of the distances and the leaves
+func copyDistances(DM *DistanceMatrix) ([][]float64, []*TreeNode) {
+	n := len(DM.IDs)
+	D := make([][]float64, n)
+	nodes := make([]*TreeNode, n)
+	for i := range D {
+		D[i] = make([]float64, n)
+		copy(D[i], DM.D[i])
+		nodes[i] = &TreeNode{Name: DM.IDs[i]}
+	}
+	return D, nodes
+}
+
+// NeighbourJoining builds an unrooted tree from a distance matrix with the
+// neighbour-joining algorithm of Saitou and Nei (1987). The tree is returned with a
+// three-way split at the root, and negative branch lengths are set to 0.
+func NeighbourJoining(DM *DistanceMatrix) (*TreeNode, error) {
+	return neighbourJoining(DM, false)
+}
+
+// BIONJ builds an unrooted tree as NeighbourJoining does, but updates the distances
+// using Gascuel's (1997) variance estimates, which usually gives more accurate trees
+// from noisy distances
+func BIONJ(DM *DistanceMatrix) (*TreeNode, error) {
+	return neighbourJoining(DM, true)
+}
+
+func neighbourJoining(DM *DistanceMatrix, bionj bool) (*TreeNode, error) {
+
+	if err := checkDistances(DM); err != nil {
+		return nil, err
+	}
+
+	D, nodes := copyDistances(DM)
+	var V [][]float64 // BIONJ's variances, which start as the distances
+	if bionj {
+		V, _ = copyDistances(DM)
+	}
+
+	// the indices of the clusters that are still active
+	active := make([]int, len(nodes))
+	for i := range active {
+		active[i] = i
+	}
+
+	for len(active) > 3 {
+		r := len(active)
+
+		sums := make(map[int]float64, r)
+		for _, i := range active {
+			for _, k := range active {
+				sums[i] += D[i][k]
+			}
+		}
+
+		// the pair minimising Q
+		bi, bj := -1, -1
+		best := math.Inf(1)
+		for a := 0; a < r; a++ {
+			for b := a + 1; b < r; b++ {
+				i, j := active[a], active[b]
+				q := float64(r-2)*D[i][j] - sums[i] - sums[j]
+				if q < best {
+					best, bi, bj = q, a, b
+				}
+			}
+		}
+		i, j := active[bi], active[bj]
+
+		li := D[i][j]/2 + (sums[i]-sums[j])/(2*float64(r-2))
+		lj := D[i][j] - li
+		nodes[i].Length = math.Max(li, 0)
+		nodes[j].Length = math.Max(lj, 0)
+
+		lambda := 0.5
+		if bionj && V[i][j] > 0 {
+			s := 0.0
+			for _, k := range active {
+				if k != i && k != j {
+					s += V[j][k] - V[i][k]
+				}
+			}
+			lambda = math.Min(math.Max(0.5+s/(2*float64(r-2)*V[i][j]), 0), 1)
+		}
+
+		// the new cluster takes i's place
+		for _, k := range active {
+			if k == i || k == j {
+				continue
+			}
+			var d float64
+			if bionj {
+				d = lambda*(D[i][k]-li) + (1-lambda)*(D[j][k]-lj)
+				v := lambda*V[i][k] + (1-lambda)*V[j][k] - lambda*(1-lambda)*V[i][j]
+				V[i][k], V[k][i] = v, v
+			} else {
+				d = (D[i][k] + D[j][k] - D[i][j]) / 2
+			}
+			D[i][k], D[k][i] = d, d
+		}
+		nodes[i] = &TreeNode{Children: []*TreeNode{nodes[i], nodes[j]}}
+		active = append(active[:bj], active[bj+1:]...)
+	}
+
+	root := &TreeNode{}
+	switch len(active) {
+	case 1:
+		return nodes[active[0]], nil
+	case 2:
+		a, b := active[0], active[1]
+		nodes[a].Length = D[a][b] / 2
+		nodes[b].Length = D[a][b] / 2
+		root.Children = []*TreeNode{nodes[a], nodes[b]}
+	case 3:
+		a, b, c := active[0], active[1], active[2]
+		nodes[a].Length = math.Max((D[a][b]+D[a][c]-D[b][c])/2, 0)
+		nodes[b].Length = math.Max((D[a][b]+D[b][c]-D[a][c])/2, 0)
+		nodes[c].Length = math.Max((D[a][c]+D[b][c]-D[a][b])/2, 0)
+		root.Children = []*TreeNode{nodes[a], nodes[b], nodes[c]}
+	}
+
+	return root, nil
+}
+
+// UPGMA builds a rooted, ultrametric tree from a distance matrix by average-linkage
+// clustering
+func UPGMA(DM *DistanceMatrix) (*TreeNode, error) {
+
+	if err := checkDistances(DM); err != nil {
+		return nil, err
+	}
+
+	D, nodes := copyDistances(DM)
+	sizes := make([]int, len(nodes))
+	heights := make([]float64, len(nodes))
+	active := make([]int, len(nodes))
+	for i := range active {
+		active[i] = i
+		sizes[i] = 1
+	}
+
+	for len(active) > 1 {
+		bi, bj := -1, -1
+		best := math.Inf(1)
+		for a := range active {
+			for b := a + 1; b < len(active); b++ {
+				if d := D[active[a]][active[b]]; d < best {
+					best, bi, bj = d, a, b
+				}
+			}
+		}
+		i, j := active[bi], active[bj]
+
+		h := D[i][j] / 2
+		nodes[i].Length = math.Max(h-heights[i], 0)
+		nodes[j].Length = math.Max(h-heights[j], 0)
+
+		for _, k := range active {
+			if k != i && k != j {
+				d := (D[i][k]*float64(sizes[i]) + D[j][k]*float64(sizes[j])) / float64(sizes[i]+sizes[j])
+				D[i][k], D[k][i] = d, d
+			}
+		}
+		nodes[i] = &TreeNode{Children: []*TreeNode{nodes[i], nodes[j]}}
+		sizes[i] += sizes[j]
+		heights[i] = h
+		active = append(active[:bj], active[bj+1:]...)
+	}
+
+	return nodes[active[0]], nil
+}
+
+// Newick returns the tree in Newick format, ending with a semicolon. Names are
+// quoted if they need to be.
+func (T *TreeNode) Newick() string {
+	var sb strings.Builder
+	T.appendNewick(&sb, true)
+	sb.WriteByte(';')
+	return sb.String()
+}
+
+func (T *TreeNode) appendNewick(sb *strings.Builder, root bool) {
+	if len(T.Children) > 0 {
+		sb.WriteByte('(')
+		for i, c := range T.Children {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			c.appendNewick(sb, false)
+		}
+		sb.WriteByte(')')
+	}
+	if T.Name != "" {
+		sb.WriteString(nexusLabel(T.Name))
+	}
+	if !root {
+		sb.WriteByte(':')
+		sb.WriteString(formatDistance(T.Length))
+	}
+}
+
+// Tips returns the names of the leaves, in Newick order
+func (T *TreeNode) Tips() []string {
+	if len(T.Children) == 0 {
+		return []string{T.Name}
+	}
+	tips := make([]string, 0)
+	for _, c := range T.Children {
+		tips = append(tips, c.Tips()...)
+	}
+	return tips
+}
+
+// WriteNewick writes the tree in Newick format, followed by a newline
+func WriteNewick(w io.Writer, T *TreeNode) error {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(T.Newick() + "\n"); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
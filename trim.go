@@ -0,0 +1,284 @@
+package main
+
+import (
+	"errors"
+	"sort"
+)
+
+var errBadFraction = errors.New("Fraction must be between 0 and 1")
+
+// selectColumns returns copies of records containing only the given alignment
+// columns, in the order given
+func selectColumns(records []FastaRecord, columns []int) []FastaRecord {
+	selected := make([]FastaRecord, len(records))
+	for i, FR := range records {
+		seq := make([]byte, len(columns))
+		for j, c := range columns {
+			seq[j] = FR.Seq[c]
+		}
+		selected[i] = FR
+		selected[i].Seq = seq
+	}
+	return selected
+}
+
+// columnGapFractions returns the fraction of records with a gap in each column
+func columnGapFractions(records []FastaRecord) ([]float64, error) {
+	if len(records) == 0 {
+		return []float64{}, nil
+	}
+	w := len(records[0].Seq)
+	gaps := make([]int, w)
+	for _, FR := range records {
+		if len(FR.Seq) != w {
+			return []float64{}, errDifferentWidths
+		}
+		for i := range FR.Seq {
+			if FR.encodedAt(i) == 244 {
+				gaps[i]++
+			}
+		}
+	}
+	fractions := make([]float64, w)
+	for i, g := range gaps {
+		fractions[i] = float64(g) / float64(len(records))
+	}
+	return fractions, nil
+}
+
+// TrimGappyColumns removes every alignment column in which more than maxGapFraction
+// of the records have a gap. It returns the trimmed copies of the records and the
+// (0-based) original columns that were kept.
+func TrimGappyColumns(records []FastaRecord, maxGapFraction float64) ([]FastaRecord, []int, error) {
+
+	if maxGapFraction < 0 || maxGapFraction > 1 {
+		return []FastaRecord{}, []int{}, errBadFraction
+	}
+
+	fractions, err := columnGapFractions(records)
+	if err != nil {
+		return []FastaRecord{}, []int{}, err
+	}
+
+	kept := make([]int, 0, len(fractions))
+	for i, f := range fractions {
+		if f <= maxGapFraction {
+			kept = append(kept, i)
+		}
+	}
+
+	return selectColumns(records, kept), kept, nil
+}
+
+// TrimGappyout chooses the gap fraction threshold automatically, in the spirit of
+// trimAl's gappyout: the column gap fractions are sorted, and the threshold is put
+// just below the largest jump between consecutive values, so that the columns on
+// the gappy side of the sharpest change are removed. If every column has the same
+// gap fraction, nothing is removed.
+func TrimGappyout(records []FastaRecord) ([]FastaRecord, []int, error) {
+
+	fractions, err := columnGapFractions(records)
+	if err != nil {
+		return []FastaRecord{}, []int{}, err
+	}
+
+	sorted := make([]float64, len(fractions))
+	copy(sorted, fractions)
+	sort.Float64s(sorted)
+
+	threshold := 1.0
+	biggest := 0.0
+	for i := 0; i+1 < len(sorted); i++ {
+		if jump := sorted[i+1] - sorted[i]; jump > biggest {
+			biggest = jump
+			threshold = sorted[i]
+		}
+	}
+
+	return TrimGappyColumns(records, threshold)
+}
+
+// A ColumnMap records which columns of an original alignment were kept after
+// columns were removed, so that positions can be traced back. All positions are 0-based.
+type ColumnMap struct {
+	kept      []int // the original column of each kept column
+	toTrimmed []int // the new column of each original column, or -1 if it was removed
+}
+
+// NewColumnMap builds a ColumnMap from the (ascending) original columns that were
+// kept from an alignment of the given width
+func NewColumnMap(kept []int, originalWidth int) *ColumnMap {
+	CM := &ColumnMap{kept: kept, toTrimmed: make([]int, originalWidth)}
+	for i := range CM.toTrimmed {
+		CM.toTrimmed[i] = -1
+	}
+	for i, c := range kept {
+		CM.toTrimmed[c] = i
+	}
+	return CM
+}
+
+// ToOriginal returns the original column of a column in the trimmed alignment
+func (CM *ColumnMap) ToOriginal(pos int) (int, bool) {
+	if pos < 0 || pos >= len(CM.kept) {
+		return -1, false
+	}
+	return CM.kept[pos], true
+}
+
+// ToTrimmed returns the column in the trimmed alignment of an original column. ok
+// is false if the column was removed.
+func (CM *ColumnMap) ToTrimmed(pos int) (int, bool) {
+	if pos < 0 || pos >= len(CM.toTrimmed) || CM.toTrimmed[pos] == -1 {
+		return -1, false
+	}
+	return CM.toTrimmed[pos], true
+}
+
+// Kept returns the original columns that were kept, in order
+func (CM *ColumnMap) Kept() []int {
+	return CM.kept
+}
+
+// Options for StripColumns
+type StripOptions struct {
+	NAsGap          bool // also remove columns made up only of gaps and Ns
+	RemoveInvariant bool // also remove columns with fewer than two different unambiguous bases
+}
+
+// StripColumns removes the columns of an alignment (encoded or not) that are
+// entirely gaps, and optionally those that are only gaps and Ns, or that are
+// invariant. In an invariant column all of the unambiguous bases are the same;
+// ambiguity codes, Ns and gaps are ignored. It returns trimmed copies of the
+// records and a ColumnMap back to the original alignment.
+func StripColumns(records []FastaRecord, opts StripOptions) ([]FastaRecord, *ColumnMap, error) {
+
+	w := 0
+	if len(records) > 0 {
+		w = len(records[0].Seq)
+	}
+
+	// for every column, whether it has any non-gap (non-N) content, and the bits of
+	// the unambiguous bases in it
+	occupied := make([]bool, w)
+	bases := make([]byte, w)
+
+	for _, FR := range records {
+		if len(FR.Seq) != w {
+			return []FastaRecord{}, nil, errDifferentWidths
+		}
+		for i := range FR.Seq {
+			e := FR.encodedAt(i)
+			if e != 244 && !(opts.NAsGap && (e == 240 || e == 242)) {
+				occupied[i] = true
+			}
+			if e&8 == 8 {
+				bases[i] |= e
+			}
+		}
+	}
+
+	kept := make([]int, 0, w)
+	for i := 0; i < w; i++ {
+		if !occupied[i] {
+			continue
+		}
+		if opts.RemoveInvariant && isSingleBase(bases[i]) {
+			continue
+		}
+		kept = append(kept, i)
+	}
+
+	return selectColumns(records, kept), NewColumnMap(kept, w), nil
+}
+
+// isSingleBase reports whether at most one of the A, C, G and T bits is set
+func isSingleBase(e byte) bool {
+	hi := e >> 4
+	return hi&(hi-1) == 0
+}
+
+// What TrimEnds does to the ends of a record
+type EndTrimMode int
+
+const (
+	EndsToN    EndTrimMode = iota // replace the ends with N
+	EndsToGap                     // replace the ends with gaps
+	EndsRemove                    // cut the ends off
+)
+
+// Options for TrimEnds
+type TrimEndsOptions struct {
+	Mode EndTrimMode
+	// If MinGoodRun is 0, the ends of the record are its leading and trailing gaps.
+	// Otherwise each end extends up to the first run of at least MinGoodRun
+	// unambiguous bases, so that ragged, low-quality ends are caught too.
+	MinGoodRun int
+}
+
+// TrimEnds converts or removes the ends of a record in place. See TrimEndsOptions.
+func (FR *FastaRecord) TrimEnds(opts TrimEndsOptions) {
+
+	L := len(FR.Seq)
+	isEnd := func(i int) bool { return FR.encodedAt(i) == 244 }
+	if opts.MinGoodRun > 0 {
+		isEnd = func(i int) bool {
+			for j := i; j < i+opts.MinGoodRun; j++ {
+				if j >= L || FR.encodedAt(j)&8 != 8 {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	start := 0
+	for start < L && isEnd(start) {
+		start++
+	}
+
+	end := L
+	if opts.MinGoodRun > 0 {
+		// scan backwards for the last good run
+		run := 0
+		for end > start {
+			if FR.encodedAt(end-1)&8 == 8 {
+				run++
+				if run == opts.MinGoodRun {
+					end += run - 1
+					break
+				}
+			} else {
+				run = 0
+			}
+			end--
+		}
+	} else {
+		for end > start && isEnd(end-1) {
+			end--
+		}
+	}
+
+	switch opts.Mode {
+	case EndsRemove:
+		FR.Seq = FR.Seq[start:end]
+	default:
+		var fill byte
+		switch {
+		case opts.Mode == EndsToN && FR.encoded:
+			fill = 240
+		case opts.Mode == EndsToN:
+			fill = 'N'
+		case FR.encoded:
+			fill = 244
+		default:
+			fill = '-'
+		}
+		for i := 0; i < start; i++ {
+			FR.Seq[i] = fill
+		}
+		for i := end; i < L; i++ {
+			FR.Seq[i] = fill
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+var errWrongAlphabet = errors.New("Sequence contains characters outside its alphabet")
+
+// An Alphabet is the type parameter of a Record, saying what kind of sequence it
+// holds, so that (for instance) a protein can't be passed where DNA is expected
+type Alphabet interface {
+	Name() string
+	Valid(b byte) bool
+}
+
+// The alphabets. DNA and RNA allow the IUPAC ambiguity codes, gaps and '?'; Protein
+// allows the 20 amino acids, B, J, O, U, X, Z, '*' for stop codons, and gaps.
+type (
+	DNA     struct{}
+	RNA     struct{}
+	Protein struct{}
+)
+
+const proteinLetters = "ACDEFGHIKLMNPQRSTVWYBJOUXZ*-"
+
+func (DNA) Name() string      { return "DNA" }
+func (DNA) Valid(b byte) bool { return encodingArray[b] != 0 }
+
+func (RNA) Name() string { return "RNA" }
+func (RNA) Valid(b byte) bool {
+	switch b {
+	case 'U', 'u':
+		return true
+	case 'T', 't':
+		return false
+	}
+	return encodingArray[b] != 0
+}
+
+func (Protein) Name() string      { return "Protein" }
+func (Protein) Valid(b byte) bool { return strings.IndexByte(proteinLetters, toUpper(b)) >= 0 }
+
+// A Record is a fasta record whose sequence is known to belong to alphabet A. Its
+// sequence is always held as plain text. FastaRecord remains the untyped record
+// that the rest of the package works on; convert with NewRecord and Untyped.
+type Record[A Alphabet] struct {
+	ID          string
+	Description string
+	seq         []byte
+	idSep       *string // as FastaRecord.idSep
+}
+
+// NewRecord checks that FR's sequence belongs to alphabet A and returns it as a
+// typed record, decoding it if it is encoded (which is only possible for DNA). The
+// sequence is copied.
+func NewRecord[A Alphabet](FR FastaRecord) (Record[A], error) {
+	var alphabet A
+	if FR.encoded {
+		if _, ok := any(alphabet).(DNA); !ok {
+			return Record[A]{}, errWrongAlphabet
+		}
+	}
+	R := Record[A]{ID: FR.ID, Description: FR.Description, idSep: FR.idSep, seq: make([]byte, len(FR.Seq))}
+	for i := range FR.Seq {
+		b := FR.Seq[i]
+		if FR.encoded {
+			b = decodingArray[b]
+		}
+		if !alphabet.Valid(b) {
+			return Record[A]{}, errWrongAlphabet
+		}
+		R.seq[i] = b
+	}
+	return R, nil
+}
+
+// Alphabet returns the name of the record's alphabet
+func (R Record[A]) Alphabet() string {
+	var alphabet A
+	return alphabet.Name()
+}
+
+// Len returns the length of the sequence
+func (R Record[A]) Len() int {
+	return len(R.seq)
+}
+
+// Seq returns a copy of the sequence
+func (R Record[A]) Seq() []byte {
+	seq := make([]byte, len(R.seq))
+	copy(seq, R.seq)
+	return seq
+}
+
+// Untyped returns the record as an unencoded FastaRecord, with a copy of its sequence
+func (R Record[A]) Untyped() FastaRecord {
+	return FastaRecord{ID: R.ID, Description: R.Description, idSep: R.idSep, Seq: R.Seq()}
+}
+
+// ReverseComplementDNA returns the reverse complement of a DNA record
+func ReverseComplementDNA(R Record[DNA]) Record[DNA] {
+	rc := Record[DNA]{ID: R.ID, Description: R.Description, idSep: R.idSep, seq: R.Seq()}
+	reverseComplementBytes(rc.seq, false)
+	return rc
+}
+
+// Transcribe returns the RNA for a DNA record, with U in place of T
+func Transcribe(R Record[DNA]) Record[RNA] {
+	rna := Record[RNA]{ID: R.ID, Description: R.Description, idSep: R.idSep, seq: R.Seq()}
+	for i, b := range rna.seq {
+		switch b {
+		case 'T':
+			rna.seq[i] = 'U'
+		case 't':
+			rna.seq[i] = 'u'
+		}
+	}
+	return rna
+}
+
+// BackTranscribe returns the DNA for an RNA record, with T in place of U
+func BackTranscribe(R Record[RNA]) Record[DNA] {
+	dna := Record[DNA]{ID: R.ID, Description: R.Description, idSep: R.idSep, seq: R.Seq()}
+	for i, b := range dna.seq {
+		switch b {
+		case 'U':
+			dna.seq[i] = 'T'
+		case 'u':
+			dna.seq[i] = 't'
+		}
+	}
+	return dna
+}
+
+// TranslateDNA translates a DNA record from its first base with the genetic code
+// GC, ignoring any incomplete codon at the end
+func TranslateDNA(R Record[DNA], GC *GeneticCode) Record[Protein] {
+	return Record[Protein]{ID: R.ID, Description: R.Description, idSep: R.idSep, seq: GC.Translate(FastaRecord{Seq: R.seq})}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var errBadlyFormedScheme = errors.New("Badly formed typing scheme line")
+
+// One defining allele of a type in a TypingScheme
+type schemeSite struct {
+	Pos    int  // 0-based alignment column
+	Allele byte // encoded
+}
+
+// A TypingScheme assigns records to types (e.g. lineages) from the alleles they
+// carry at defining positions
+type TypingScheme struct {
+	// The smallest fraction of a type's defining sites at which a record must have
+	// exactly the defining allele to be assigned that type. Whatever it is, at least
+	// one site must match exactly.
+	MinCalled float64
+
+	labels []string
+	sites  map[string][]schemeSite
+}
+
+// ReadTypingScheme reads a tab-separated scheme with one defining allele per line:
+// the 1-based alignment position, the allele (one IUPAC character), and the label
+// that it defines. A label can (and usually does) have several lines. Blank lines
+// and lines starting with # are skipped.
+func ReadTypingScheme(r io.Reader) (*TypingScheme, error) {
+
+	TS := &TypingScheme{labels: make([]string, 0), sites: make(map[string][]schemeSite)}
+	s := bufio.NewScanner(r)
+
+	for s.Scan() {
+		line := strings.TrimRight(s.Text(), "\r")
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 3 || len(fields[1]) != 1 || fields[2] == "" {
+			return nil, errBadlyFormedScheme
+		}
+		pos, err := strconv.Atoi(fields[0])
+		if err != nil || pos < 1 {
+			return nil, errBadlyFormedScheme
+		}
+		allele := encodingArray[fields[1][0]]
+		if allele == 0 {
+			return nil, errBadlyFormedScheme
+		}
+		label := fields[2]
+		if _, ok := TS.sites[label]; !ok {
+			TS.labels = append(TS.labels, label)
+		}
+		TS.sites[label] = append(TS.sites[label], schemeSite{Pos: pos - 1, Allele: allele})
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return TS, nil
+}
+
+// Labels returns the scheme's labels, in the order they first appear in the file
+func (TS *TypingScheme) Labels() []string {
+	return TS.labels
+}
+
+// The type assigned to one record
+type TypingResult struct {
+	RecordID  string
+	Label     string // empty if no type matched
+	Sites     int    // the number of defining sites of the type
+	Matches   int    // sites where the record has exactly the defining allele
+	Ambiguous int    // sites where the record is ambiguous but could have it (including N)
+}
+
+// Assign types every record in an alignment (encoded or not). A record matches a
+// type if none of the type's defining sites rules it out: an ambiguity code is
+// allowed if it includes the defining allele. But a type is only considered if the
+// record has the defining allele itself at at least one of its sites, and at at
+// least MinCalled of them, so that a record that is mostly N isn't given a type it
+// has no evidence for. Of the types that match, the record is assigned the one
+// with the most exact matches, and then with the most defining sites (i.e. the
+// most specific one). A record that matches no type gets an empty Label. Ties go
+// to the type that comes first in the scheme.
+func (TS *TypingScheme) Assign(aln []FastaRecord) ([]TypingResult, error) {
+
+	w := 0
+	if len(aln) > 0 {
+		w = len(aln[0].Seq)
+	}
+	for _, sites := range TS.sites {
+		for _, s := range sites {
+			if s.Pos >= w {
+				return []TypingResult{}, errSeqBounds
+			}
+		}
+	}
+
+	results := make([]TypingResult, len(aln))
+
+	for i := range aln {
+		FR := &aln[i]
+		if len(FR.Seq) != w {
+			return []TypingResult{}, errDifferentWidths
+		}
+
+		best := TypingResult{RecordID: FR.ID}
+		for _, label := range TS.labels {
+			TR := TypingResult{RecordID: FR.ID, Label: label, Sites: len(TS.sites[label])}
+			mismatches := 0
+			for _, s := range TS.sites[label] {
+				e := FR.encodedAt(s.Pos)
+				switch {
+				case e == s.Allele:
+					TR.Matches++
+				case e != 244 && s.Allele != 244 && e&s.Allele >= 16:
+					TR.Ambiguous++
+				default:
+					mismatches++
+				}
+			}
+			if mismatches > 0 || TR.Matches == 0 || float64(TR.Matches) < TS.MinCalled*float64(TR.Sites) {
+				continue
+			}
+			if best.Label == "" || TR.Matches > best.Matches || (TR.Matches == best.Matches && TR.Sites > best.Sites) {
+				best = TR
+			}
+		}
+		results[i] = best
+	}
+
+	return results, nil
+}
+
+// WriteTypingReport writes the results of Assign as TSV, with "unassigned" for
+// records that matched no type. The results are written in the order given.
+func WriteTypingReport(w io.Writer, results []TypingResult) error {
+
+	c := csv.NewWriter(w)
+	c.Comma = '\t'
+	if err := c.Write([]string{"id", "type", "sites", "matches", "ambiguous"}); err != nil {
+		return err
+	}
+
+	for _, TR := range results {
+		label := TR.Label
+		if label == "" {
+			label = "unassigned"
+		}
+		err := c.Write([]string{TR.RecordID, label, strconv.Itoa(TR.Sites), strconv.Itoa(TR.Matches), strconv.Itoa(TR.Ambiguous)})
+		if err != nil {
+			return err
+		}
+	}
+
+	c.Flush()
+	return c.Error()
+}
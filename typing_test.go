@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTypingSchemeMissingData(t *testing.T) {
+	scheme := "1\tA\tB\n2\tC\tB\n1\tA\tB.1\n2\tC\tB.1\n3\tG\tB.1\n4\tT\tB.1\n"
+	TS, err := ReadTypingScheme(strings.NewReader(scheme))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aln := []FastaRecord{
+		{ID: "allN", Seq: []byte("NNNN")},
+		{ID: "gaps", Seq: []byte("----")},
+		{ID: "full", Seq: []byte("ACGT")},
+		{ID: "partial", Seq: []byte("ACNN")},
+		{ID: "one", Seq: []byte("ANNN")},
+	}
+
+	want := []string{"", "", "B.1", "B.1", "B.1"}
+	results, err := TS.Assign(aln)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, TR := range results {
+		if TR.Label != want[i] {
+			t.Errorf("%s: got %q, want %q", TR.RecordID, TR.Label, want[i])
+		}
+	}
+
+	TS.MinCalled = 0.75
+	want = []string{"", "", "B.1", "B", ""}
+	results, err = TS.Assign(aln)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, TR := range results {
+		if TR.Label != want[i] {
+			t.Errorf("MinCalled 0.75, %s: got %q, want %q", TR.RecordID, TR.Label, want[i])
+		}
+	}
+}
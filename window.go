@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Statistics for one window of a sequence. Start and End are 0-based, half-open.
+type WindowStats struct {
+	Start     int
+	End       int
+	GC        float64 // GC% of the unambiguous bases
+	GCSkew    float64 // (G - C) / (G + C)
+	NFraction float64 // fraction of the window that is N (or ?)
+	Entropy   float64 // Shannon entropy of the unambiguous bases, in bits
+}
+
+var errBadWindow = errors.New("Window size and step must be greater than zero")
+
+// SlidingWindows returns statistics for windows of the given size along a record
+// (which may be encoded or not), moving step bases each time. The final window is
+// truncated at the end of the sequence if it would otherwise overrun it.
+func SlidingWindows(FR FastaRecord, size, step int) ([]WindowStats, error) {
+
+	if size < 1 || step < 1 {
+		return []WindowStats{}, errBadWindow
+	}
+
+	windows := make([]WindowStats, 0, len(FR.Seq)/step+1)
+
+	// counts of each encoded byte within the current window, which is updated
+	// incrementally as the window slides instead of being recounted
+	var counts [256]int
+	lo, hi := 0, 0
+
+	for start := 0; start < len(FR.Seq); start += step {
+		end := start + size
+		if end > len(FR.Seq) {
+			end = len(FR.Seq)
+		}
+
+		if start >= hi {
+			counts = [256]int{}
+			lo, hi = start, start
+		}
+		for ; lo < start; lo++ {
+			counts[FR.encodedAt(lo)]--
+		}
+		for ; hi < end; hi++ {
+			counts[FR.encodedAt(hi)]++
+		}
+
+		windows = append(windows, windowStatsFromCounts(start, end, &counts))
+
+		if end == len(FR.Seq) {
+			break
+		}
+	}
+
+	return windows, nil
+}
+
+func windowStatsFromCounts(start, end int, counts *[256]int) WindowStats {
+	a, c, g, t := counts[136], counts[40], counts[72], counts[24]
+	n := counts[240] + counts[242]
+
+	WS := WindowStats{Start: start, End: end}
+	if end > start {
+		WS.NFraction = float64(n) / float64(end-start)
+	}
+	if g+c > 0 {
+		WS.GCSkew = float64(g-c) / float64(g+c)
+	}
+	acgt := a + c + g + t
+	if acgt > 0 {
+		WS.GC = 100 * float64(g+c) / float64(acgt)
+		for _, x := range []int{a, c, g, t} {
+			if x > 0 {
+				p := float64(x) / float64(acgt)
+				WS.Entropy -= p * math.Log2(p)
+			}
+		}
+	}
+	return WS
+}
+
+// WriteBedGraph writes one bedGraph line per window, with the value for each window
+// given by the value function, e.g.
+//
+//	WriteBedGraph(w, "chr1", windows, func(WS WindowStats) float64 { return WS.GC })
+func WriteBedGraph(w io.Writer, chrom string, windows []WindowStats, value func(WindowStats) float64) error {
+	bw := bufio.NewWriter(w)
+	for _, WS := range windows {
+		if _, err := fmt.Fprintf(bw, "%s\t%d\t%d\t%g\n", chrom, WS.Start, WS.End, value(WS)); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteWig writes the windows as a fixedStep wiggle track. The windows are
+// assumed to come from one call to SlidingWindows with the given size and step.
+func WriteWig(w io.Writer, chrom string, size, step int, windows []WindowStats, value func(WindowStats) float64) error {
+	bw := bufio.NewWriter(w)
+	if len(windows) > 0 {
+		if _, err := fmt.Fprintf(bw, "fixedStep chrom=%s start=%d step=%d span=%d\n", chrom, windows[0].Start+1, step, size); err != nil {
+			return err
+		}
+	}
+	for _, WS := range windows {
+		if _, err := fmt.Fprintf(bw, "%g\n", value(WS)); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+)
+
+// A Writer writes fasta records to an underlying io.Writer. As with csv.Writer, the
+// exported fields can be changed after NewWriter returns and before the first Write.
+type Writer struct {
+	LineWidth        int  // wrap sequences at this many characters; 0 for no wrapping
+	WriteAnnotations bool // append each record's annotations to its header as key=value pairs
+	Recycle          bool // return each record's Seq to the record pool once it is written (see GetRecord)
+	// Canonical output is the same for any two semantically identical alignments, so
+	// that files can be hashed and cached: sequences are uppercase and wrapped at
+	// DefaultLineWidth (whatever LineWidth is), whitespace in headers is collapsed to
+	// single spaces, and WriteAll sorts the records by ID.
+	Canonical bool
+	UseCRLF   bool // end lines with \r\n instead of \n (not in Canonical mode)
+
+	w   *bufio.Writer
+	buf []byte
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{LineWidth: DefaultLineWidth, w: bufio.NewWriter(w)}
+}
+
+// Write writes one record, decoding its sequence if it is encoded. Output is buffered,
+// so Flush must be called when writing is finished.
+func (w *Writer) Write(FR FastaRecord) error {
+	header := FR.header()
+	if w.WriteAnnotations {
+		header = FR.annotatedHeader()
+	}
+	width := w.LineWidth
+	if w.Canonical {
+		header = strings.Join(strings.Fields(header), " ")
+		width = DefaultLineWidth
+	}
+	w.buf = appendFasta(w.buf[:0], header, &FR, width)
+	if w.Canonical {
+		for i := len(header) + 2; i < len(w.buf); i++ {
+			w.buf[i] = toUpper(w.buf[i])
+		}
+	}
+	var err error
+	if w.UseCRLF && !w.Canonical {
+		err = w.writeCRLF(w.buf)
+	} else {
+		_, err = w.w.Write(w.buf)
+	}
+	if w.Recycle {
+		recycleSeq(FR.Seq)
+	}
+	return err
+}
+
+// writeCRLF writes buf with each \n replaced by \r\n
+func (w *Writer) writeCRLF(buf []byte) error {
+	for len(buf) > 0 {
+		i := bytes.IndexByte(buf, '\n')
+		if i < 0 {
+			_, err := w.w.Write(buf)
+			return err
+		}
+		if _, err := w.w.Write(buf[:i]); err != nil {
+			return err
+		}
+		if _, err := w.w.WriteString("\r\n"); err != nil {
+			return err
+		}
+		buf = buf[i+1:]
+	}
+	return nil
+}
+
+// WriteAll writes every record and then flushes the Writer. In Canonical mode the
+// records are written in order of ID (and then sequence, for duplicate IDs), without
+// reordering the slice that is passed in.
+func (w *Writer) WriteAll(records []FastaRecord) error {
+	if w.Canonical {
+		sorted := make([]FastaRecord, len(records))
+		copy(sorted, records)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			if sorted[i].ID != sorted[j].ID {
+				return sorted[i].ID < sorted[j].ID
+			}
+			return sorted[i].Compare(&sorted[j]) < 0
+		})
+		records = sorted
+	}
+	for _, FR := range records {
+		if err := w.Write(FR); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// Flush writes any buffered data to the underlying io.Writer
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}